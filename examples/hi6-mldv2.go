@@ -0,0 +1,51 @@
+// Version 2 Multicast Listener Report
+package main
+
+import (
+	"fmt"
+	"github.com/BobBurns/hackicmp6/hi6"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("must specify interface!")
+		os.Exit(-1)
+	}
+	t := hi6.ICMP6{
+		Iface:  os.Args[1],
+		DstIP:  "ff02::16",
+		SrcIP:  "", // should be inferred from interface
+		SrcMAC: "", // should be inferred from interface
+		DstMAC: "", // should be inferred from multicast
+		Type:   hi6.ICMPTypeVersion2MulticastListenerReport,
+		Code:   0,
+	}
+	t.AddMLDRecord(hi6.MLDRecord{
+		Type: hi6.MLD_CHANGE_TO_EXCLUDE,
+		Addr: "ff05::1:3",
+	})
+	t.AddMLDRecord(hi6.MLDRecord{
+		Type:    hi6.MLD_ALLOW_NEW_SOURCES,
+		Addr:    "ff3e::8000:1",
+		Sources: []string{"2001:db8::1", "2001:db8::2"},
+	})
+
+	err := t.BuildICMPPacket()
+	if err != nil {
+		fmt.Println("errors found...")
+		fmt.Println(err)
+		fmt.Println("exiting.")
+		os.Exit(-1)
+	}
+	for {
+		fmt.Printf(".")
+		err = t.Send()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		time.Sleep(time.Duration(1 * time.Second))
+	}
+}
@@ -0,0 +1,55 @@
+// Node Information Query for the host names of a node
+package main
+
+import (
+	"fmt"
+	"github.com/BobBurns/hackicmp6/hi6"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Println("must specify interface and target address!")
+		os.Exit(-1)
+	}
+	l := hi6.Listener{
+		Iface: os.Args[1],
+		Types: []hi6.ICMPType{hi6.ICMPTypeNodeInformationResponse},
+	}
+	c, err := l.Listen()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	defer l.Close()
+
+	t := hi6.ICMP6{
+		Iface:      os.Args[1],
+		DstIP:      os.Args[2],
+		Type:       hi6.ICMPTypeNodeInformationQuery,
+		Code:       hi6.NI_SUBJECT_IPV6,
+		NI_Qtype:   hi6.NI_QTYPE_NODE_NAME,
+		NI_Subject: os.Args[2],
+	}
+	err = t.BuildICMPPacket()
+	if err == nil {
+		err = l.Send(&t)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	timeout := time.After(time.Duration(3 * time.Second))
+	for {
+		select {
+		case r := <-c:
+			if r.NI_Nonce == t.NI_Nonce {
+				fmt.Println(r.SrcIP, r.NI_Names)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
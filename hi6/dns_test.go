@@ -0,0 +1,43 @@
+package hi6
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDNSNames(t *testing.T) {
+	b, err := dnsEncodeName("www.example.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte("\x03www\x07example\x00")
+	if !bytes.Equal(b, want) {
+		t.Fatalf("% x", b)
+	}
+	names := dnsDecodeNames(append(append(b, 0, 0), "\x02ex\x00"...))
+	if !reflect.DeepEqual(names, []string{"www.example", "ex"}) {
+		t.Fatalf("%q", names)
+	}
+	for _, bad := range []string{"a..b", strings.Repeat("x", 64) + ".example"} {
+		if _, err := dnsEncodeName(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseDNSSL(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_DNSSL, DNSSL_Lifetime: 1200, DNSSL_Domains: []string{"example.com", "corp.example.net"}})
+	p.AddOption(Option{Type: OPT_MTU, MTU: 1500})
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 2 || q.Options[1].MTU != 1500 {
+		t.Fatalf("%+v", q.Options)
+	}
+	o := q.Options[0]
+	if o.DNSSL_Lifetime != 1200 || !reflect.DeepEqual(o.DNSSL_Domains, p.Options[0].DNSSL_Domains) {
+		t.Fatalf("DNSSL %+v", o)
+	}
+}
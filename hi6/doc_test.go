@@ -1,4 +1,9 @@
-package hi6
+package hi6_test
+
+import (
+	"github.com/BobBurns/hackicmp6/hi6"
+	"time"
+)
 
 func Example() {
 
@@ -0,0 +1,266 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// IPv6 Extension Header Types
+const (
+	EXT_HOPBYHOP = 0
+	EXT_ROUTING  = 43
+	EXT_FRAGMENT = 44
+	EXT_DSTOPTS  = 60
+)
+
+// Hop-by-Hop and Destination Options Types
+const (
+	EXTOPT_PAD1         = 0
+	EXTOPT_PADN         = 1
+	EXTOPT_ROUTER_ALERT = 5
+)
+
+// Router Alert values
+const (
+	ROUTER_ALERT_MLD = 0
+)
+
+// ExtHeader is an IPv6 Extension Header. Headers in ICMP6.ExtHeaders
+// are chained in order between the IPv6 header and the ICMP6 message
+type ExtHeader struct {
+	// Extension Header Type (ie EXT_HOPBYHOP, EXT_DSTOPTS)
+	Type int
+
+	// Hop-by-Hop and Destination Options. The header is
+	// padded to 8 bytes with Pad1/PadN
+	Options []ExtOption
+
+	// Routing Header Type, Segments Left and Addresses. The
+	// Addresses follow 4 reserved bytes as in Type 0 and Type 2.
+	// The ICMP6 checksum uses the last address if Segments Left
+	// is not 0 (the first one for Type 4 Segment Routing)
+	RoutingType  int
+	SegmentsLeft int
+	Addresses    []string
+
+	// Fragment Offset in 8 byte units, M flag and Identification
+	FragOffset int
+	FragMore   bool
+	FragID     uint32
+
+	// If set, used as is after the Next Header and Hdr Ext Len
+	// bytes instead of building from the fields above. Padded
+	// with zeros to 8 bytes
+	Raw []byte
+
+	// If not 0, written to Hdr Ext Len instead of the real length
+	Length int
+}
+
+// ExtOption is a TLV option for Hop-by-Hop and Destination Options
+type ExtOption struct {
+	Type int
+	Data []byte
+}
+
+// Convenience function to add Extension Headers to ICMP6 struct
+func (t *ICMP6) AddExtHeader(e ExtHeader) {
+	t.ExtHeaders = append(t.ExtHeaders, e)
+}
+
+// RouterAlert returns a Router Alert option with value v
+// for a Hop-by-Hop header
+func RouterAlert(v uint16) ExtOption {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, v)
+	return ExtOption{Type: EXTOPT_ROUTER_ALERT, Data: data}
+}
+
+// the Extension Headers to build. MLD and MRD messages get a Hop-by-Hop
+// Router Alert unless NoRouterAlert is set or the user already
+// supplied a Hop-by-Hop header (ie to send a malformed one)
+func (t *ICMP6) extHeaders() []ExtHeader {
+	if !needsRouterAlert(t.Type) || t.NoRouterAlert {
+		return t.ExtHeaders
+	}
+	if len(t.ExtHeaders) > 0 && t.ExtHeaders[0].Type == EXT_HOPBYHOP {
+		return t.ExtHeaders
+	}
+	hbh := ExtHeader{
+		Type:    EXT_HOPBYHOP,
+		Options: []ExtOption{RouterAlert(ROUTER_ALERT_MLD)},
+	}
+	return append([]ExtHeader{hbh}, t.ExtHeaders...)
+}
+
+// messages that must carry a Router Alert and a Hop Limit of 1
+func needsRouterAlert(typ ICMPType) bool {
+	switch typ {
+	case ICMPTypeMulticastListenerQuery, ICMPTypeMulticastListenerReport,
+		ICMPTypeMulticastListenerDone, ICMPTypeVersion2MulticastListenerReport,
+		ICMPTypeMulticastRouterAdvertisement, ICMPTypeMulticastRouterSolicitation,
+		ICMPTypeMulticastRouterTermination:
+		return true
+	}
+	return false
+}
+
+// marshal exts chained in order ending with upper layer protocol
+// proto. Returns the headers and the Next Header value for the IPv6 header
+func marshalExtHeaders(exts []ExtHeader, proto int) ([]byte, int, error) {
+	var b []byte
+	for i := range exts {
+		nh := proto
+		if i+1 < len(exts) {
+			nh = exts[i+1].Type
+		}
+		eb, err := exts[i].marshal(nh)
+		if err != nil {
+			return nil, 0, err
+		}
+		b = append(b, eb...)
+	}
+	if len(exts) == 0 {
+		return nil, proto, nil
+	}
+	return b, exts[0].Type, nil
+}
+
+// marshal returns the binary encoding of e with Next Header nh
+func (e *ExtHeader) marshal(nh int) ([]byte, error) {
+	extErr := errors.New("Error building Extension Header")
+
+	b := []byte{byte(nh), 0}
+	switch {
+	case e.Raw != nil:
+		b = append(b, e.Raw...)
+	case e.Type == EXT_HOPBYHOP || e.Type == EXT_DSTOPTS:
+		for _, o := range e.Options {
+			if o.Type == EXTOPT_PAD1 {
+				b = append(b, EXTOPT_PAD1)
+				continue
+			}
+			if len(o.Data) > 255 {
+				fmt.Println("Extension Header option", o.Type, "data longer than 255 bytes")
+				return nil, extErr
+			}
+			b = append(b, byte(o.Type), byte(len(o.Data)))
+			b = append(b, o.Data...)
+		}
+		b = padExtOptions(b)
+	case e.Type == EXT_ROUTING:
+		b = append(b, byte(e.RoutingType), byte(e.SegmentsLeft), 0, 0, 0, 0)
+		for _, a := range e.Addresses {
+			ip := net.ParseIP(a)
+			if ip == nil {
+				fmt.Println("Routing Header: could not parse address", a)
+				return nil, extErr
+			}
+			b = append(b, ip.To16()...)
+		}
+	case e.Type == EXT_FRAGMENT:
+		b = append(b, 0, 0, 0, 0, 0, 0)
+		fo := uint16(e.FragOffset << 3)
+		if e.FragMore {
+			fo |= 1
+		}
+		binary.BigEndian.PutUint16(b[2:4], fo)
+		binary.BigEndian.PutUint32(b[4:8], e.FragID)
+	default:
+		fmt.Println("Unknown Extension Header type", e.Type, "needs Raw")
+		return nil, extErr
+	}
+
+	// zero pad anything left to 8 bytes
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	if e.Type != EXT_FRAGMENT {
+		if len(b)/8-1 > 255 && e.Length == 0 {
+			fmt.Println("Extension Header longer than 2048 bytes")
+			return nil, extErr
+		}
+		b[1] = byte(len(b)/8 - 1)
+	}
+	if e.Length != 0 {
+		b[1] = byte(e.Length)
+	}
+	return b, nil
+}
+
+// pad Hop-by-Hop and Destination Options to 8 bytes with Pad1 or PadN
+func padExtOptions(b []byte) []byte {
+	pad := (8 - len(b)%8) % 8
+	switch {
+	case pad == 1:
+		b = append(b, EXTOPT_PAD1)
+	case pad > 1:
+		b = append(b, EXTOPT_PADN, byte(pad-2))
+		b = append(b, make([]byte, pad-2)...)
+	}
+	return b
+}
+
+// decode an extension header. b holds the whole header
+func parseExtHeader(typ int, b []byte) ExtHeader {
+	e := ExtHeader{Type: typ}
+	switch typ {
+	case EXT_HOPBYHOP, EXT_DSTOPTS:
+		ob := b[2:]
+		for len(ob) > 0 {
+			if ob[0] == EXTOPT_PAD1 {
+				e.Options = append(e.Options, ExtOption{Type: EXTOPT_PAD1})
+				ob = ob[1:]
+				continue
+			}
+			if len(ob) < 2 || 2+int(ob[1]) > len(ob) {
+				// malformed, keep the bytes as they are
+				e.Options = nil
+				e.Raw = append([]byte(nil), b[2:]...)
+				break
+			}
+			o := ExtOption{
+				Type: int(ob[0]),
+				Data: append([]byte(nil), ob[2:2+int(ob[1])]...),
+			}
+			e.Options = append(e.Options, o)
+			ob = ob[2+int(ob[1]):]
+		}
+	case EXT_ROUTING:
+		e.RoutingType = int(b[2])
+		e.SegmentsLeft = int(b[3])
+		if e.RoutingType != 0 && e.RoutingType != 2 && e.RoutingType != 4 {
+			e.Raw = append([]byte(nil), b[2:]...)
+			break
+		}
+		for ab := b[8:]; len(ab) >= 16; ab = ab[16:] {
+			e.Addresses = append(e.Addresses, net.IP(ab[0:16]).String())
+		}
+	case EXT_FRAGMENT:
+		fo := binary.BigEndian.Uint16(b[2:4])
+		e.FragOffset = int(fo >> 3)
+		e.FragMore = fo&1 != 0
+		e.FragID = binary.BigEndian.Uint32(b[4:8])
+	}
+	return e
+}
+
+// the destination used in the upper layer checksum. With a Routing
+// Header that is the last hop rather than the IPv6 Destination
+func finalDst(exts []ExtHeader, dst net.IP) net.IP {
+	for _, e := range exts {
+		if e.Type != EXT_ROUTING || e.SegmentsLeft == 0 || len(e.Addresses) == 0 {
+			continue
+		}
+		a := e.Addresses[len(e.Addresses)-1]
+		if e.RoutingType == 4 {
+			a = e.Addresses[0]
+		}
+		if ip := net.ParseIP(a); ip != nil {
+			dst = ip
+		}
+	}
+	return dst
+}
@@ -0,0 +1,104 @@
+package hi6
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseExtHeaders(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoRequest, ICMP6_id: 1}
+	p.AddExtHeader(ExtHeader{Type: EXT_HOPBYHOP, Options: []ExtOption{{Type: 0x3e, Data: []byte{1, 2, 3}}}})
+	p.AddExtHeader(ExtHeader{Type: EXT_ROUTING, RoutingType: 0, SegmentsLeft: 1, Addresses: []string{"2001:db8::9"}})
+	p.AddExtHeader(ExtHeader{Type: EXT_DSTOPTS, Options: []ExtOption{{Type: EXTOPT_PAD1}}})
+	q := roundTrip(t, &p)
+
+	if len(q.ExtHeaders) != 3 {
+		t.Fatalf("got %d extension headers", len(q.ExtHeaders))
+	}
+	hbh := q.ExtHeaders[0]
+	if hbh.Type != EXT_HOPBYHOP || hbh.Options[0].Type != 0x3e || !bytes.Equal(hbh.Options[0].Data, []byte{1, 2, 3}) {
+		t.Fatalf("Hop-by-Hop %+v", hbh)
+	}
+	rh := q.ExtHeaders[1]
+	if rh.Type != EXT_ROUTING || rh.SegmentsLeft != 1 || len(rh.Addresses) != 1 || rh.Addresses[0] != "2001:db8::9" {
+		t.Fatalf("Routing %+v", rh)
+	}
+	if q.ExtHeaders[2].Type != EXT_DSTOPTS || q.Type != ICMPTypeEchoRequest || q.ICMP6_id != 1 {
+		t.Fatalf("%+v", q)
+	}
+
+	// the parsed headers build the same frame again
+	q.Iface = "lo"
+	want, _ := p.MarshalBinary()
+	if err := q.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := q.MarshalBinary()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("rebuilt\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestExtOptionTooLong(t *testing.T) {
+	e := ExtHeader{Type: EXT_DSTOPTS, Options: []ExtOption{{Type: 0x1e, Data: make([]byte, 256)}}}
+	if _, err := e.marshal(58); err == nil {
+		t.Fatal("expected error for option data over 255 bytes")
+	}
+	e.Options[0].Data = e.Options[0].Data[:255]
+	b, err := e.marshal(58)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b)%8 != 0 || b[3] != 255 || int(b[1]) != len(b)/8-1 {
+		t.Fatalf("header length %d, Hdr Ext Len %d", len(b), b[1])
+	}
+}
+
+func TestMLDRouterAlert(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeMulticastListenerReport, MLD_Addr: "ff02::1:3"}
+	q := roundTrip(t, &p)
+
+	if q.HopLimit != 1 {
+		t.Fatalf("Hop Limit %d", q.HopLimit)
+	}
+	if len(q.ExtHeaders) != 1 || q.ExtHeaders[0].Type != EXT_HOPBYHOP {
+		t.Fatalf("no Hop-by-Hop header %+v", q.ExtHeaders)
+	}
+	ra := q.ExtHeaders[0].Options[0]
+	if ra.Type != EXTOPT_ROUTER_ALERT || !bytes.Equal(ra.Data, []byte{0, 0}) {
+		t.Fatalf("Router Alert %+v", ra)
+	}
+	if q.MLD_Addr != "ff02::1:3" {
+		t.Fatalf("MLD Address %s", q.MLD_Addr)
+	}
+
+	p.NoRouterAlert = true
+	p.HopLimit = 5
+	q = roundTrip(t, &p)
+	if len(q.ExtHeaders) != 0 || q.HopLimit != 5 {
+		t.Fatalf("%+v %d", q.ExtHeaders, q.HopLimit)
+	}
+}
+
+func TestHopLimitZero(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55", Type: ICMPTypeEchoRequest}
+	if q := roundTrip(t, &p); q.HopLimit != 64 {
+		t.Fatalf("default Hop Limit %d", q.HopLimit)
+	}
+
+	p.HopLimitOverride = true
+	q := roundTrip(t, &p)
+	if q.HopLimit != 0 || !q.HopLimitOverride {
+		t.Fatalf("Hop Limit %d", q.HopLimit)
+	}
+	// a parsed packet builds with the same Hop Limit
+	q.Iface = "lo"
+	if err := q.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := q.MarshalBinary()
+	if b[EtherLen+7] != 0 {
+		t.Fatalf("rebuilt Hop Limit %d", b[EtherLen+7])
+	}
+}
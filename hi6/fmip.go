@@ -0,0 +1,100 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// FMIPv6 Subtypes
+const (
+	FMIP_RTSOLPR = 2
+	FMIP_PRRTADV = 3
+)
+
+// FMIPv6 Proxy Router Advertisement Codes
+const (
+	FMIP_PRRTADV_NEW_AP      = 0
+	FMIP_PRRTADV_NO_CHANGE   = 1
+	FMIP_PRRTADV_CURRENT_AP  = 2
+	FMIP_PRRTADV_UNKNOWN_AP  = 3
+	FMIP_PRRTADV_UNSOLICITED = 4
+	FMIP_PRRTADV_NETWORK     = 5
+)
+
+// FMIPv6 IP Address/Prefix Option Codes
+const (
+	FMIP_IP_OLD_COA    = 1
+	FMIP_IP_NEW_COA    = 2
+	FMIP_IP_NAR_ADDR   = 3
+	FMIP_IP_NAR_PREFIX = 4
+)
+
+// FMIPv6 Link-Layer Address Option Codes
+const (
+	FMIP_LLA_WILDCARD  = 0
+	FMIP_LLA_NEW_AP    = 1
+	FMIP_LLA_MN        = 2
+	FMIP_LLA_NAR       = 3
+	FMIP_LLA_SOURCE    = 4
+	FMIP_LLA_AP_ON_NAR = 5
+)
+
+// FMIPv6 option lengths
+const (
+	fmipIPPrefixLen = 24
+	fmipLLAHeader   = 3
+)
+
+// IP Address/Prefix option
+func (o *Option) fmipIPPrefix() ([]byte, error) {
+	b := make([]byte, fmipIPPrefixLen)
+	b[0] = OPT_IP_ADDR_PREFIX
+	b[1] = fmipIPPrefixLen / 8
+	b[2] = byte(o.FMIP_Code)
+	b[3] = byte(o.PI_Prefix_Len)
+	/* 4 - 7 Reserved */
+	ip := net.ParseIP(o.Addr)
+	if ip == nil {
+		fmt.Println("FMIPv6: could not parse IP address", o.Addr)
+		return nil, errors.New("Error building IP Address/Prefix option")
+	}
+	copy(b[8:24], ip.To16())
+	return b, nil
+}
+
+// Link-Layer Address option. No address for FMIP_LLA_WILDCARD
+func (o *Option) fmipLinkAddr() ([]byte, error) {
+	b := []byte{OPT_LINKADDR, 0, byte(o.FMIP_Code)}
+	if o.Addr != "" {
+		addr, err := net.ParseMAC(o.Addr)
+		if err != nil {
+			fmt.Println("Could not Parse LinkAddr")
+			return nil, errors.New("Error building Link-Layer Address option")
+		}
+		b = append(b, addr...)
+	}
+	return padOption(b), nil
+}
+
+// decode an IP Address/Prefix or Link-Layer Address option.
+// b holds the whole option
+func (o *Option) parseFMIP(b []byte) {
+	o.FMIP_Code = int(b[2])
+	switch {
+	case o.Type == OPT_IP_ADDR_PREFIX && len(b) >= fmipIPPrefixLen:
+		o.PI_Prefix_Len = int(b[3])
+		o.Addr = net.IP(b[8:24]).String()
+	case o.Type == OPT_LINKADDR && len(b) >= fmipLLAHeader+6:
+		if o.FMIP_Code != FMIP_LLA_WILDCARD {
+			o.Addr = net.HardwareAddr(b[fmipLLAHeader : fmipLLAHeader+6]).String()
+		}
+	}
+}
+
+// decode the FMIPv6 header data. Options follow the header
+func (t *ICMP6) parseFMIP(data []byte) {
+	t.FMIP_Subtype = int(data[0])
+	t.ICMP6_id = binary.BigEndian.Uint16(data[2:4])
+}
@@ -0,0 +1,34 @@
+package hi6
+
+import "testing"
+
+func TestParseFMIPPrRtAdv(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeFMIPv6, Code: FMIP_PRRTADV_NEW_AP, FMIP_Subtype: FMIP_PRRTADV, ICMP6_id: 11}
+	p.AddOption(Option{Type: OPT_LINKADDR, FMIP_Code: FMIP_LLA_NEW_AP, Addr: "10:0b:a9:aa:aa:aa"})
+	p.AddOption(Option{Type: OPT_LINKADDR, FMIP_Code: FMIP_LLA_NAR, Addr: "10:0b:a9:bb:bb:bb"})
+	p.AddOption(Option{Type: OPT_IP_ADDR_PREFIX, FMIP_Code: FMIP_IP_NAR_PREFIX, PI_Prefix_Len: 64, Addr: "2001:db8:2::"})
+	q := roundTrip(t, &p)
+
+	if q.FMIP_Subtype != FMIP_PRRTADV || q.Code != FMIP_PRRTADV_NEW_AP || q.ICMP6_id != 11 || len(q.Options) != 3 {
+		t.Fatalf("%+v", q)
+	}
+	if o := q.Options[1]; o.FMIP_Code != FMIP_LLA_NAR || o.Addr != "10:0b:a9:bb:bb:bb" {
+		t.Fatalf("Link-Layer Address %+v", o)
+	}
+	if o := q.Options[2]; o.FMIP_Code != FMIP_IP_NAR_PREFIX || o.PI_Prefix_Len != 64 || o.Addr != "2001:db8:2::" {
+		t.Fatalf("IP Address/Prefix %+v", o)
+	}
+}
+
+func TestParseFMIPRtSolPr(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeFMIPv6, FMIP_Subtype: FMIP_RTSOLPR, ICMP6_id: 12}
+	p.AddOption(Option{Type: OPT_LINKADDR, FMIP_Code: FMIP_LLA_WILDCARD})
+	q := roundTrip(t, &p)
+
+	if q.FMIP_Subtype != FMIP_RTSOLPR || q.ICMP6_id != 12 || len(q.Options) != 1 ||
+		q.Options[0].FMIP_Code != FMIP_LLA_WILDCARD || q.Options[0].Addr != "" {
+		t.Fatalf("%+v", q)
+	}
+}
@@ -0,0 +1,174 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/songgao/ether"
+	"github.com/songgao/packets/ethernet"
+	"net"
+)
+
+// Fragment is one piece of a fragmented packet. Fragments returns
+// them ready to send, but any field can be changed before calling
+// SendFragments
+type Fragment struct {
+	// Fragment Offset in bytes. Encoded in 8 byte units
+	Offset int
+
+	// M flag. Set on every fragment but the last
+	More bool
+
+	// Fragment Identification
+	ID uint32
+
+	// Next Header of the Fragment header
+	NextHeader int
+
+	// The part of the fragmentable payload carried
+	Data []byte
+}
+
+// Fragments splits the built packet into fragments of FragSize bytes
+// with FragID, FragOverlap and FragOrder applied. The IPv6 header,
+// Hop-by-Hop and Routing headers (and Destination Options before a
+// Routing header) are the unfragmentable part repeated in each one
+func (t *ICMP6) Fragments() ([]Fragment, error) {
+	fErr := errors.New("Error building fragments")
+
+	if len(t.frame) == 0 {
+		fmt.Println("Must build headers first!")
+		return nil, fErr
+	}
+	size := t.FragSize &^ 7
+	overlap := t.FragOverlap &^ 7
+	if size < 8 || overlap >= size {
+		fmt.Println("FragSize must be at least 8 and more than FragOverlap")
+		return nil, fErr
+	}
+
+	nhPos, unfrag := unfragmentable(t.frame)
+	payload := t.frame[unfrag:]
+
+	var frags []Fragment
+	for start := 0; ; start += size - overlap {
+		end := start + size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		frags = append(frags, Fragment{
+			Offset:     start,
+			More:       end < len(payload),
+			ID:         t.FragID,
+			NextHeader: int(t.frame[nhPos]),
+			Data:       payload[start:end],
+		})
+		if end == len(payload) {
+			break
+		}
+	}
+
+	if len(t.FragOrder) == 0 {
+		return frags, nil
+	}
+	ordered := make([]Fragment, 0, len(t.FragOrder))
+	for _, i := range t.FragOrder {
+		if i < 0 || i >= len(frags) {
+			fmt.Println("FragOrder index out of range", i)
+			return nil, fErr
+		}
+		ordered = append(ordered, frags[i])
+	}
+	return ordered, nil
+}
+
+// SendFragments sends frags in order on one device
+func (t *ICMP6) SendFragments(frags []Fragment) error {
+	sErr := errors.New("Error sending fragments")
+
+	frames, err := t.fragmentFrames(frags)
+	if err != nil {
+		return sErr
+	}
+
+	hwIface, err := net.InterfaceByName(t.Iface)
+	if err != nil {
+		fmt.Println(err)
+		return sErr
+	}
+	ff := func(frame ethernet.Frame) bool { return false }
+	myDev, err := ether.NewDev(hwIface, ff)
+	if err != nil {
+		fmt.Println("Error getting interface", err)
+		return sErr
+	}
+	defer myDev.Close()
+
+	if err = t.writeFrames(myDev, frames); err != nil {
+		return sErr
+	}
+	return nil
+}
+
+// the Ethernet frames of frags
+func (t *ICMP6) fragmentFrames(frags []Fragment) ([]ethernet.Frame, error) {
+	var frames []ethernet.Frame
+	for _, f := range frags {
+		myFrame, err := t.fragmentFrame(f)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, myFrame)
+	}
+	return frames, nil
+}
+
+// build the Ethernet frame for one fragment
+func (t *ICMP6) fragmentFrame(f Fragment) (ethernet.Frame, error) {
+	nhPos, unfrag := unfragmentable(t.frame)
+
+	pkt := make([]byte, unfrag+8+len(f.Data))
+	copy(pkt, t.frame[:unfrag])
+	pkt[nhPos] = EXT_FRAGMENT
+	binary.BigEndian.PutUint16(pkt[4:6], uint16(len(pkt)-IPHeaderLen))
+
+	fh := pkt[unfrag : unfrag+8]
+	fh[0] = byte(f.NextHeader)
+	fh[1] = 0
+	fo := uint16(f.Offset &^ 7)
+	if f.More {
+		fo |= 1
+	}
+	binary.BigEndian.PutUint16(fh[2:4], fo)
+	binary.BigEndian.PutUint32(fh[4:8], f.ID)
+	copy(pkt[unfrag+8:], f.Data)
+
+	return t.etherFrame(pkt)
+}
+
+// find the end of the unfragmentable part of an IPv6 packet and
+// the position of the Next Header byte that points past it
+func unfragmentable(pkt []byte) (int, int) {
+	nhPos := 6
+	unfrag := IPHeaderLen
+
+	nh := int(pkt[6])
+	off := IPHeaderLen
+	for off+8 <= len(pkt) {
+		if nh != EXT_HOPBYHOP && nh != EXT_ROUTING && nh != EXT_DSTOPTS {
+			break
+		}
+		l := (int(pkt[off+1]) + 1) * 8
+		if off+l > len(pkt) {
+			break
+		}
+		// Destination Options only count before a Routing header
+		if nh != EXT_DSTOPTS {
+			nhPos = off
+			unfrag = off + l
+		}
+		nh = int(pkt[off])
+		off += l
+	}
+	return nhPos, unfrag
+}
@@ -0,0 +1,88 @@
+package hi6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func fragPacket(t *testing.T) *ICMP6 {
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoRequest, Data: data, DataLen: len(data), FragSize: 64, FragID: 0x1234}
+	p.AddExtHeader(ExtHeader{Type: EXT_HOPBYHOP, Options: []ExtOption{RouterAlert(0)}})
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	return &p
+}
+
+func TestFragments(t *testing.T) {
+	p := fragPacket(t)
+	frags, err := p.Fragments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, err := p.fragmentFrames(frags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole, _ := p.MarshalBinary()
+	// IPv6 and Hop-by-Hop headers are unfragmentable
+	unfrag := EtherLen + IPHeaderLen + 8
+	payload := whole[unfrag:]
+
+	var reassembled []byte
+	for i, f := range frames {
+		if !bytes.Equal(f[EtherLen+8:EtherLen+40], whole[EtherLen+8:EtherLen+40]) {
+			t.Fatalf("fragment %d addresses differ", i)
+		}
+		if f[EtherLen+IPHeaderLen] != EXT_FRAGMENT {
+			t.Fatalf("fragment %d Hop-by-Hop does not point to Fragment header", i)
+		}
+		if int(binary.BigEndian.Uint16(f[EtherLen+4:])) != len(f)-EtherLen-IPHeaderLen {
+			t.Fatalf("fragment %d payload length", i)
+		}
+		fh := f[unfrag : unfrag+8]
+		fo := binary.BigEndian.Uint16(fh[2:4])
+		if fh[0] != 58 || binary.BigEndian.Uint32(fh[4:8]) != 0x1234 {
+			t.Fatalf("fragment %d header % x", i, fh)
+		}
+		if int(fo&^7) != len(reassembled) || (fo&1 == 1) != (i < len(frames)-1) {
+			t.Fatalf("fragment %d offset %d", i, fo)
+		}
+		if len(f)-unfrag-8 > 64 {
+			t.Fatalf("fragment %d too large", i)
+		}
+		reassembled = append(reassembled, f[unfrag+8:]...)
+	}
+	if !bytes.Equal(reassembled, payload) {
+		t.Fatal("reassembled payload differs")
+	}
+}
+
+func TestFragmentsOverlapOrder(t *testing.T) {
+	p := fragPacket(t)
+	p.FragOverlap = 16
+	p.FragOrder = []int{2, 0}
+	frags, err := p.Fragments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frags) != 2 || frags[0].Offset != 96 || frags[1].Offset != 0 {
+		t.Fatalf("%+v", frags)
+	}
+
+	p.FragOrder = []int{9}
+	if _, err := p.Fragments(); err == nil {
+		t.Fatal("expected error for FragOrder out of range")
+	}
+	p.FragOrder = nil
+	p.FragOverlap = 64
+	if _, err := p.Fragments(); err == nil {
+		t.Fatal("expected error for FragOverlap not less than FragSize")
+	}
+}
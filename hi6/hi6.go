@@ -4,6 +4,8 @@
 package hi6
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -12,6 +14,7 @@ import (
 	"net"
 	"strings"
 	"syscall"
+	"time"
 )
 
 // Various header lengths
@@ -21,6 +24,9 @@ const (
 	ICMPHeaderLen = 8
 )
 
+// shortest ICMP6 message: type, code and checksum
+const icmpMinLen = 4
+
 // ICMP6 Option Header Types
 const (
 	OPT_SOURCE_LINKADDR    = 1
@@ -28,7 +34,21 @@ const (
 	OPT_PREFIX_INFORMATION = 3
 	OPT_REDIRECT_HEADER    = 4
 	OPT_MTU                = 5
+	OPT_SOURCE_ADDR_LIST   = 9
+	OPT_TARGET_ADDR_LIST   = 10
+	OPT_CGA                = 11
+	OPT_RSA_SIGNATURE      = 12
+	OPT_TIMESTAMP          = 13
+	OPT_NONCE              = 14
+	OPT_TRUST_ANCHOR       = 15
+	OPT_CERTIFICATE        = 16
+	OPT_IP_ADDR_PREFIX     = 17
+	OPT_LINKADDR           = 19
+	OPT_ROUTE_INFORMATION  = 24
 	OPT_RDNS               = 25
+	OPT_DNSSL              = 31
+	OPT_ADDR_REGISTRATION  = 33
+	OPT_6LOWPAN_CONTEXT    = 34
 )
 
 // ICMP6 Option Header Prefix Info Flags
@@ -112,6 +132,22 @@ type ICMP6 struct {
 	// of the interface
 	PreferGlobal bool
 
+	// IPv6 Hop Limit. If 0 uses 64, or 1 for MLD messages
+	HopLimit int
+
+	// Set to true to use HopLimit as is, even 0. Parse sets it
+	HopLimitOverride bool
+
+	// MLD messages get a Hop-by-Hop header with a Router Alert
+	// option. Set to true to leave it out. To send a malformed
+	// Router Alert put your own Hop-by-Hop header first in ExtHeaders
+	NoRouterAlert bool
+
+	// If set, every frame written by Send is recorded here
+	// (see NewPcapWriter and NewPcapngWriter). Use Record for
+	// frames that are built but not sent
+	Capture FrameWriter
+
 	// ICMP Type
 	Type ICMPType
 
@@ -121,9 +157,24 @@ type ICMP6 struct {
 	// ICMP Data. Should be empty if builing Packet from Type
 	ICMPData [4]byte
 
+	// ICMP Checksum. Filled in by Parse
+	Checksum uint16
+
+	// Set by Parse when Checksum matches the pseudo header
+	// checksum of the received packet
+	ChecksumValid bool
+
 	// ICMP Payload. To use for building raw ICMP Packets
 	Data []byte
 
+	// Invoking packet to embed in Destination Unreachable, Packet Too Big,
+	// Time Exceeded and Parameter Problem messages. Truncated so the
+	// error fits in MinMTU. Parse decodes it from received errors
+	Invoking *InvokingPacket
+
+	// Raw invoking packet. Used when Invoking is nil
+	InvokingData []byte
+
 	// ICMP Payload length
 	DataLen int
 
@@ -133,6 +184,10 @@ type ICMP6 struct {
 	// Data Field for ICMP6 Parameter Problem
 	ICMP6_pptr uint32 /* parameter prob */
 
+	// If set, BuildICMPPacket fills in ICMP6_pptr and Code for this
+	// field of the invoking packet. See ParameterProblemPointer
+	PP_Field *FieldRef
+
 	// Data Field for ICMP6 Packet Too Big
 	ICMP6_mtu uint32
 
@@ -145,6 +200,23 @@ type ICMP6 struct {
 	// Data Field for ICMP6 Echo Request/Reply Sequence
 	ICMP6_maxdelay uint16 /* mcast group membership */
 
+	// Extended Echo Request L-bit. Set if the probed
+	// interface is on the proxy node. Extended Echo uses
+	// ICMP6_id and the low 8 bits of ICMP6_seq
+	XE_Local bool
+
+	// Extended Echo Request interface to probe. Set the name,
+	// address or index
+	XE_IfName  string
+	XE_IfAddr  string
+	XE_IfIndex uint32
+
+	// Extended Echo Reply State and Active, IPv4, IPv6 bits
+	XE_State  int
+	XE_Active bool
+	XE_IPv4   bool
+	XE_IPv6   bool
+
 	// Target IP6 Address. Use for Neighbor Advertisement
 	// and Neighbor Solicitation
 	TargetAddr string
@@ -176,6 +248,116 @@ type ICMP6 struct {
 	// Multicast Listener Discovery IP6 Address
 	MLD_Addr string
 
+	// Set to true to build an MLDv2 Multicast Listener Query.
+	// MLD_MaxDelay is then the Maximum Response Code
+	MLD_V2 bool
+
+	// MLDv2 Query Suppress Router-Side Processing flag
+	MLD_SFlag bool
+
+	// MLDv2 Query Querier's Robustness Variable
+	MLD_QRV int
+
+	// MLDv2 Query Querier's Query Interval Code
+	MLD_QQIC int
+
+	// MLDv2 Query Source Addresses
+	MLD_Sources []string
+
+	// Version 2 Multicast Listener Report Address Records
+	MLD_Records []MLDRecord
+
+	// Mobile Prefix Advertisement Flags (ie MIP_FLAG_MANAGED).
+	// Mobile IPv6 messages use ICMP6_id as the Identifier
+	MIP_Flags int
+
+	// Home Agent Address Discovery Reply Home Agent Addresses
+	MIP_HomeAgents []string
+
+	// Certification Path Solicitation/Advertisement Component
+	// and Advertisement All Components. Both messages use
+	// ICMP6_id as the Identifier
+	CP_Component     int
+	CP_AllComponents int
+
+	// FMIPv6 Subtype (ie FMIP_RTSOLPR). FMIPv6 uses ICMP6_id
+	// as the Identifier
+	FMIP_Subtype int
+
+	// ILNPv6 Locator Update Operation and Locators
+	ILNP_Operation int
+	ILNP_Locators  []ILNPLocator
+
+	// MPL Control Seed Info entries
+	MPL_Seeds []MPLSeed
+
+	// Multicast Router Advertisement Query Interval and
+	// Robustness Variable. The Code is the Advertisement Interval
+	MRD_QueryInterval uint16
+	MRD_Robustness    uint16
+
+	// Node Information Qtype (ie NI_QTYPE_NODE_NAME). The Code
+	// of a Query gives the Subject type (ie NI_SUBJECT_IPV6)
+	NI_Qtype int
+
+	// Node Information Flags
+	NI_Flags int
+
+	// Node Information Nonce. Generated if all zeros
+	NI_Nonce [8]byte
+
+	// Node Information Query Subject. An IPv6 address, DNS name
+	// or IPv4 address. Empty for no Subject (ie NOOP)
+	NI_Subject string
+
+	// Node Information Reply TTL and Names for Node Name
+	NI_TTL   uint32
+	NI_Names []string
+
+	// Node Information Reply Addresses for Node Addresses
+	// and IPv4 Addresses
+	NI_Addrs []NIAddr
+
+	// RPL Control RPLInstanceID. The Code selects the
+	// message (ie RPL_DIO)
+	RPL_InstanceID int
+
+	// RPL DIO Version Number and Rank
+	RPL_Version int
+	RPL_Rank    int
+
+	// RPL DIO Grounded flag, Mode of Operation and DODAG Preference
+	RPL_Grounded bool
+	RPL_MOP      int
+	RPL_Prf      int
+
+	// RPL DIO Destination Advertisement Trigger Sequence Number
+	RPL_DTSN int
+
+	// RPL DODAGID. Optional for DAO and DAO-ACK
+	RPL_DODAGID string
+
+	// RPL DAO K flag to request a DAO-ACK
+	RPL_AckReq bool
+
+	// RPL DAO and DAO-ACK Sequence and DAO-ACK Status
+	RPL_DAOSeq int
+	RPL_Status int
+
+	// RPL Control Options
+	RPL_Options []RPLOption
+
+	// Duplicate Address Request/Confirmation Status, TID and
+	// Registration Lifetime in minutes
+	DA_Status   int
+	DA_TID      int
+	DA_Lifetime uint16
+
+	// Duplicate Address Request/Confirmation EUI-64 or ROVR
+	// (zero padded to 8 bytes) and Registered Address
+	DA_ROVR []byte
+	DA_Addr string
+
 	// Router Renumbering Sequence Number
 	RR_Seqnum int
 
@@ -198,8 +380,31 @@ type ICMP6 struct {
 	// Prefix Info, MTU)
 	Options []Option
 
+	// IPv6 Extension Headers (ie Hop-by-Hop, Destination Options,
+	// Routing, Fragment) inserted between the IPv6 header and
+	// the ICMP6 message
+	ExtHeaders []ExtHeader
+
+	// If not 0, Send splits the packet into fragments carrying at
+	// most FragSize bytes (rounded down to 8) of the fragmentable part
+	FragSize int
+
+	// Fragment Identification for every fragment
+	FragID uint32
+
+	// Bytes each fragment overlaps the one before it (rounded down to 8)
+	FragOverlap int
+
+	// Send fragments in this order by index. Indexes may be
+	// left out or repeated. If empty send in order
+	FragOrder []int
+
 	// internal
 	frame ethernet.Frame
+
+	// RSA Signature option and its index in Data
+	sigOpt *Option
+	sigPos int
 }
 
 // Option Struct to add options to a few ICMP6 Packets
@@ -210,6 +415,19 @@ type Option struct {
 	// IP6 Address for Source/Target LinkAddrss
 	Addr string
 
+	// Raw option bytes including type and length. If set, sent
+	// as is whatever the Type. Parse uses it for options hi6
+	// does not know how to decode
+	Raw []byte
+
+	// Set LengthOverride to true to write Length to the Length
+	// byte instead of the real length, even 0
+	Length         int
+	LengthOverride bool
+
+	// IP6 Addresses for Source/Target Address List
+	Addrs []string
+
 	// Option Prefix Info
 	RA_Reachable  uint32
 	RA_Retransmit uint32
@@ -219,12 +437,73 @@ type Option struct {
 	PI_Pref_Time  uint32
 	MTU           uint32
 
+	// DNS Search List Lifetime and Domain Names
+	DNSSL_Lifetime uint32
+	DNSSL_Domains  []string
+
+	// Route Information Preference (ie RA_FLAG_PREF_HIGH) and
+	// Lifetime. Uses PI_Prefix_Len and Addr as the Prefix
+	RIO_Pref     byte
+	RIO_Lifetime uint32
+
 	// Recursive DNS Servers
 	RDNS_Lifetime uint32
 
-	// Only supporting 2
+	// Recursive DNS Servers sent before RDNS_Servers if set.
+	// Parse only fills RDNS_Servers
 	RDNS_Server1 string
 	RDNS_Server2 string
+
+	// Any number of Recursive DNS Servers
+	RDNS_Servers []string
+
+	// Address Registration Status, Opaque, Flags (ie
+	// ARO_FLAG_REGISTRATION), TID, Registration Lifetime in
+	// minutes and EUI-64 or ROVR (zero padded to 8 bytes)
+	ARO_Status   int
+	ARO_Opaque   int
+	ARO_Flags    int
+	ARO_TID      int
+	ARO_Lifetime uint16
+	ARO_ROVR     []byte
+
+	// Trust Anchor Name Type (ie TA_NAME_DER) and Name. An FQDN
+	// in TA_FQDN is DNS encoded into the Name
+	TA_NameType int
+	TA_Name     []byte
+	TA_FQDN     string
+
+	// Certificate Type (ie CERT_X509V3) and DER encoded Certificate
+	CERT_Type int
+	CERT_Data []byte
+
+	// FMIPv6 IP Address/Prefix and Link-Layer Address Option-Code.
+	// Addr is the address and PI_Prefix_Len the Prefix Length
+	FMIP_Code int
+
+	// CGA Parameters. See CGAOption
+	CGA_Params []byte
+
+	// RSA Signature key. The Key Hash and Digital Signature are
+	// computed from the key over the final packet unless
+	// SIG_KeyHash or SIG_Data are set (ie to send a bad signature).
+	// Without SIG_Key both SIG_KeyHash and SIG_Data are needed
+	SIG_Key     *rsa.PrivateKey
+	SIG_KeyHash []byte
+	SIG_Data    []byte
+
+	// Timestamp. The current time is filled in if zero
+	TS_Time time.Time
+
+	// Nonce. 6 random bytes are filled in if empty
+	Nonce []byte
+
+	// 6LoWPAN Context Length, C flag, Context ID and Valid
+	// Lifetime in minutes. Addr is the Context Prefix
+	CO_Len      int
+	CO_Compress bool
+	CO_CID      int
+	CO_Lifetime uint16
 }
 
 // Router Renumbering PCO Match Header
@@ -276,6 +555,11 @@ func (t *ICMP6) verifyAddr() error {
 		return vErr
 	}
 
+	// Multicast Router Discovery default destinations
+	if t.DstIP == "" {
+		t.DstIP = mrdDstIP(t.Type)
+	}
+
 	// Destination MAC
 	if t.DstMAC == "" {
 		if strings.HasPrefix(t.DstIP, "ff") || strings.HasPrefix(t.DstIP, "FF") {
@@ -382,18 +666,35 @@ func (t *ICMP6) BuildICMPPacket() error {
 	// debug
 	//fmt.Println("payload len data:", p.PayloadLen)
 
+	exts := t.extHeaders()
+	ext, nh, err := marshalExtHeaders(exts, syscall.IPPROTO_ICMPV6)
+	if err != nil {
+		return bErr
+	}
+
 	h.Version = 6
 	h.TrafficClass = 0x00
-	h.NextHeader = syscall.IPPROTO_ICMPV6
+	h.NextHeader = nh
 	h.HopLimit = 64
+	if t.HopLimit != 0 || t.HopLimitOverride {
+		h.HopLimit = t.HopLimit
+	} else if needsRouterAlert(t.Type) {
+		h.HopLimit = 1
+	}
 	h.Src = net.ParseIP(t.SrcIP)
 
 	/* copy src to icmp struct for pseudo header */
 	p.Src = net.ParseIP(t.SrcIP)
 	h.Dst = net.ParseIP(t.DstIP)
 
-	/* copy dst to icmp struct for pseudo header */
-	p.Dst = net.ParseIP(t.DstIP)
+	/* copy final dst to icmp struct for pseudo header */
+	p.Dst = finalDst(exts, net.ParseIP(t.DstIP))
+
+	if t.Type == ICMPTypeParameterProblem && t.PP_Field != nil {
+		if err := t.setParameterProblem(); err != nil {
+			return bErr
+		}
+	}
 
 	p.Type = int(t.Type)
 	p.Code = t.Code
@@ -402,17 +703,51 @@ func (t *ICMP6) BuildICMPPacket() error {
 
 	// Build ICMP data from ICMP6 Struct
 	offset := 0
-	if t.Type == ICMPTypeParameterProblem {
+	if t.Type == ICMPTypeDestinationUnreachable || t.Type == ICMPTypeTimeExceeded {
+		// unused
+		binary.BigEndian.PutUint32(p.Data[:4], uint32(0))
+	} else if t.Type == ICMPTypeParameterProblem {
 		binary.BigEndian.PutUint32(p.Data[:4], t.ICMP6_pptr)
 	} else if t.Type == ICMPTypePacketTooBig {
 		binary.BigEndian.PutUint32(p.Data[:4], t.ICMP6_mtu)
 	} else if t.Type == ICMPTypeEchoRequest || t.Type == ICMPTypeEchoReply {
 		binary.BigEndian.PutUint16(p.Data[0:2], t.ICMP6_id)
 		binary.BigEndian.PutUint16(p.Data[2:4], t.ICMP6_seq)
+	} else if t.Type == ICMPTypeExtendedEchoRequest || t.Type == ICMPTypeExtendedEchoReply {
+		binary.BigEndian.PutUint16(p.Data[0:2], t.ICMP6_id)
+		p.Data[2] = byte(t.ICMP6_seq)
+		if t.Type == ICMPTypeExtendedEchoReply {
+			p.Data[3] = byte(t.XE_State << 5)
+			if t.XE_Active {
+				p.Data[3] |= XE_FLAG_ACTIVE
+			}
+			if t.XE_IPv4 {
+				p.Data[3] |= XE_FLAG_IPV4
+			}
+			if t.XE_IPv6 {
+				p.Data[3] |= XE_FLAG_IPV6
+			}
+		} else {
+			if t.XE_Local {
+				p.Data[3] = XE_FLAG_LOCAL
+			}
+			body, err := t.xechoBody()
+			if err != nil {
+				return bErr
+			}
+			offset = p.setBody(body)
+		}
+
 	} else if t.Type == ICMPTypeRouterSolicitation {
 		// reserved
 		binary.BigEndian.PutUint32(p.Data[0:4], uint32(0))
 
+	} else if t.Type == ICMPTypeInverseNeighborDiscoverySolicitation ||
+		t.Type == ICMPTypeInverseNeighborDiscoveryAdvertisement {
+		// reserved
+		binary.BigEndian.PutUint32(p.Data[0:4], uint32(0))
+		t.checkINDOptions()
+
 	} else if t.Type == ICMPTypeRouterAdvertisement {
 		p.Data[0] = byte(t.RA_Curhoplimit)
 		p.Data[1] = byte(t.RA_Flags)
@@ -463,6 +798,29 @@ func (t *ICMP6) BuildICMPPacket() error {
 		addr = dstIP.To16()
 		copy(p.Payload[16:32], addr)
 
+	} else if t.Type == ICMPTypeMulticastListenerQuery && t.MLD_V2 {
+
+		binary.BigEndian.PutUint16(p.Data[:2], uint16(t.MLD_MaxDelay))
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(0))
+
+		body, err := t.mldv2Query()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
+	} else if t.Type == ICMPTypeVersion2MulticastListenerReport {
+
+		// reserved
+		binary.BigEndian.PutUint16(p.Data[:2], uint16(0))
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(len(t.MLD_Records)))
+
+		body, err := t.mldv2Report()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
 	} else if t.Type == ICMPTypeMulticastListenerQuery ||
 		t.Type == ICMPTypeMulticastListenerReport || t.Type == ICMPTypeMulticastListenerDone {
 
@@ -479,6 +837,113 @@ func (t *ICMP6) BuildICMPPacket() error {
 		addr := ip.To16()
 		copy(p.Payload[0:16], addr)
 
+	} else if t.Type == ICMPTypeHomeAgentAddressDiscoveryRequest || t.Type == ICMPTypeMobilePrefixSolicitation {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.ICMP6_id)
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(0))
+
+	} else if t.Type == ICMPTypeHomeAgentAddressDiscoveryReply {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.ICMP6_id)
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(0))
+
+		body, err := t.haadBody()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
+	} else if t.Type == ICMPTypeMobilePrefixAdvertisement {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.ICMP6_id)
+		p.Data[2] = byte(t.MIP_Flags)
+		p.Data[3] = 0
+
+	} else if t.Type == ICMPTypeCertificationPathSolicitation {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.ICMP6_id)
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(t.CP_Component))
+
+	} else if t.Type == ICMPTypeCertificationPathAdvertisement {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.ICMP6_id)
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(t.CP_AllComponents))
+		offset = p.setBody(t.cpaBody())
+
+	} else if t.Type == ICMPTypeFMIPv6 {
+
+		p.Data[0] = byte(t.FMIP_Subtype)
+		p.Data[1] = 0
+		binary.BigEndian.PutUint16(p.Data[2:4], t.ICMP6_id)
+
+	} else if t.Type == ICMPTypeILNPv6LocatorUpdate {
+
+		p.Data[0] = byte(len(t.ILNP_Locators))
+		p.Data[1] = byte(t.ILNP_Operation)
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(0))
+
+		body, err := t.ilnpBody()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
+	} else if t.Type == ICMPTypeMPLControl {
+
+		body, err := t.mplBody()
+		if err != nil {
+			return bErr
+		}
+		// the Seed Info starts in the header data and
+		// may end before it
+		n := copy(p.Data[:4], body)
+		p.Short = 4 - n
+		offset = p.setBody(body[n:])
+
+	} else if t.Type == ICMPTypeMulticastRouterAdvertisement {
+
+		binary.BigEndian.PutUint16(p.Data[:2], t.MRD_QueryInterval)
+		binary.BigEndian.PutUint16(p.Data[2:4], t.MRD_Robustness)
+
+	} else if t.Type == ICMPTypeMulticastRouterSolicitation || t.Type == ICMPTypeMulticastRouterTermination {
+		// only type, code and checksum
+		p.Short = 4
+
+	} else if t.Type == ICMPTypeNodeInformationQuery || t.Type == ICMPTypeNodeInformationResponse {
+
+		binary.BigEndian.PutUint16(p.Data[:2], uint16(t.NI_Qtype))
+		binary.BigEndian.PutUint16(p.Data[2:4], uint16(t.NI_Flags))
+
+		body, err := t.niBody()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
+	} else if t.Type == ICMPTypeRPLControl {
+
+		body, err := t.rplBody()
+		if err != nil {
+			return bErr
+		}
+		// the base object starts in the header data and
+		// may end before it (ie a DIS without options)
+		n := copy(p.Data[:4], body)
+		p.Short = 4 - n
+		offset = p.setBody(body[n:])
+
+	} else if t.Type == ICMPTypeDuplicateAddressRequest || t.Type == ICMPTypeDuplicateAddressConfirmation {
+
+		p.Data[0] = byte(t.DA_Status)
+		p.Data[1] = byte(t.DA_TID)
+		binary.BigEndian.PutUint16(p.Data[2:4], t.DA_Lifetime)
+
+		body, err := t.darBody()
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(body)
+
 	} else if t.Type == ICMPTypeRouterRenumbering {
 
 		binary.BigEndian.PutUint32(p.Data[:4], uint32(t.RR_Seqnum))
@@ -550,12 +1015,22 @@ func (t *ICMP6) BuildICMPPacket() error {
 		p.PayloadLen += offset
 	}
 
+	// error messages carry the invoking packet
+	if isErrorMessage(t.Type) && (t.Invoking != nil || t.InvokingData != nil) {
+		inv, err := t.invokingPacket(len(ext))
+		if err != nil {
+			return bErr
+		}
+		offset = p.setBody(inv)
+	}
+
 	// this will overwrite any data options above
 	if t.UseICMPData == true {
 		fmt.Println("over-writing ICMPData")
 		copy(p.Data[:4], t.ICMPData[:4])
 	}
 
+	t.sigOpt = nil
 	if len(t.Options) > 0 {
 		opOff, err := t.buildOptions()
 		if err != nil {
@@ -564,7 +1039,7 @@ func (t *ICMP6) BuildICMPPacket() error {
 		}
 		p.PayloadLen += opOff
 	}
-	h.PayloadLen = ICMPHeaderLen + p.PayloadLen
+	h.PayloadLen = len(ext) + ICMPHeaderLen - p.Short + p.PayloadLen
 
 	// should take care of raw data and data tacked
 	// on to options
@@ -574,6 +1049,15 @@ func (t *ICMP6) BuildICMPPacket() error {
 		return bErr
 	}
 
+	// large payloads (ie many options) do not fit in the
+	// default buffer. They can be sent with FragSize
+	need := offset + len(t.Data)
+	if p.PayloadLen > need {
+		need = p.PayloadLen
+	}
+	if need > len(p.Payload) {
+		p.Payload = append(p.Payload, make([]byte, need-len(p.Payload))...)
+	}
 	copy(p.Payload[offset:], t.Data[:])
 
 	ip, err = h.marshal()
@@ -585,8 +1069,14 @@ func (t *ICMP6) BuildICMPPacket() error {
 	if err != nil {
 		return bErr
 	}
+	if t.sigOpt != nil {
+		if err := t.signSEND(h, p, icmp, ICMPHeaderLen-p.Short+offset+t.sigPos); err != nil {
+			return bErr
+		}
+	}
 
-	t.frame = append(ip, icmp...)
+	t.frame = append(ip, ext...)
+	t.frame = append(t.frame, icmp...)
 	return nil
 }
 
@@ -594,93 +1084,237 @@ func (t *ICMP6) BuildICMPPacket() error {
 func (t *ICMP6) buildOptions() (int, error) {
 	offset := 0
 	optErr := errors.New("Error buildOptions")
-	for _, o := range t.Options {
+	for i, o := range t.Options {
 		var optionData []byte
 
-		switch o.Type {
-		case OPT_SOURCE_LINKADDR:
-			offset = 8
-			optionData = make([]byte, offset)
-			optionData[0] = OPT_SOURCE_LINKADDR
-			optionData[1] = 1 /* length * 8 */
-			addr, err := net.ParseMAC(o.Addr)
-			if err != nil {
-				fmt.Println("Could not Parse LinkAddr")
-				return 0, optErr
-			}
-			copy(optionData[2:], addr)
-		case OPT_TARGET_LINKADDR:
-			offset = 8
-			optionData = make([]byte, offset)
-			optionData[0] = OPT_TARGET_LINKADDR
-			optionData[1] = 1 /* length * 8 */
-			addr, err := net.ParseMAC(o.Addr)
-			if err != nil {
-				fmt.Println("Could not Parse LinkAddr")
-				return 0, optErr
-			}
-			copy(optionData[2:], addr)
-		case OPT_PREFIX_INFORMATION:
-			offset = 32
-			optionData = make([]byte, offset)
-			optionData[0] = OPT_PREFIX_INFORMATION
-			optionData[1] = 4 /* length * 32 */
-			optionData[2] = byte(o.PI_Prefix_Len)
-			optionData[3] = o.PI_Flags
-			binary.BigEndian.PutUint32(optionData[4:8], o.PI_Valid_Time)
-			binary.BigEndian.PutUint32(optionData[8:12], o.PI_Pref_Time)
-			/* 12 - 15 Reserved */
-			addr := net.ParseIP(o.Addr).To16()
-			if addr != nil {
-				copy(optionData[16:32], addr)
-			} else {
-				fmt.Println("Bad IP6 Prefix Address")
-				return 0, optErr
-			}
-		case OPT_REDIRECT_HEADER:
-			/* not supported */
-		case OPT_MTU:
-			offset = 8
-			optionData = make([]byte, offset)
-			optionData[0] = OPT_MTU
-			optionData[1] = 1 /* length * 8 */
-			optionData[2] = 0
-			optionData[3] = 0
-			binary.BigEndian.PutUint32(optionData[4:], o.MTU)
-		case OPT_RDNS:
-			length := 0
-			if o.RDNS_Server2 == "" {
-				length = 3
-			} else {
-				length = 5
-			}
-			offset = length * 8
-			optionData = make([]byte, offset)
-			optionData[0] = OPT_RDNS
-			optionData[1] = byte(length)
-			binary.BigEndian.PutUint16(optionData[2:4], 0)
-			binary.BigEndian.PutUint32(optionData[4:8], o.RDNS_Lifetime)
-
-			addr := net.ParseIP(o.RDNS_Server1).To16()
-			if addr != nil {
-				copy(optionData[8:24], addr)
-			} else {
-				fmt.Println("Bad IP6 RDNS Server Address")
-				return 0, optErr
-			}
-			if o.RDNS_Server2 != "" {
-
-				addr = net.ParseIP(o.RDNS_Server2).To16()
+		// Raw is sent as is whatever the Type
+		if o.Raw != nil {
+			optionData = append(optionData, o.Raw...)
+			offset = len(optionData)
+		} else {
+			switch o.Type {
+			case OPT_SOURCE_LINKADDR:
+				offset = 8
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_SOURCE_LINKADDR
+				optionData[1] = 1 /* length * 8 */
+				addr, err := net.ParseMAC(o.Addr)
+				if err != nil {
+					fmt.Println("Could not Parse LinkAddr")
+					return 0, optErr
+				}
+				copy(optionData[2:], addr)
+			case OPT_TARGET_LINKADDR:
+				offset = 8
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_TARGET_LINKADDR
+				optionData[1] = 1 /* length * 8 */
+				addr, err := net.ParseMAC(o.Addr)
+				if err != nil {
+					fmt.Println("Could not Parse LinkAddr")
+					return 0, optErr
+				}
+				copy(optionData[2:], addr)
+			case OPT_PREFIX_INFORMATION:
+				offset = 32
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_PREFIX_INFORMATION
+				optionData[1] = 4 /* length * 32 */
+				optionData[2] = byte(o.PI_Prefix_Len)
+				optionData[3] = o.PI_Flags
+				binary.BigEndian.PutUint32(optionData[4:8], o.PI_Valid_Time)
+				binary.BigEndian.PutUint32(optionData[8:12], o.PI_Pref_Time)
+				/* 12 - 15 Reserved */
+				addr := net.ParseIP(o.Addr).To16()
 				if addr != nil {
-					copy(optionData[24:], addr)
+					copy(optionData[16:32], addr)
 				} else {
-					fmt.Println("Bad IP6 RDNS Server Address")
+					fmt.Println("Bad IP6 Prefix Address")
 					return 0, optErr
 				}
-			}
-
-		default:
+			case OPT_REDIRECT_HEADER:
+				/* not supported */
+			case OPT_MTU:
+				offset = 8
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_MTU
+				optionData[1] = 1 /* length * 8 */
+				optionData[2] = 0
+				optionData[3] = 0
+				binary.BigEndian.PutUint32(optionData[4:], o.MTU)
+			case OPT_SOURCE_ADDR_LIST, OPT_TARGET_ADDR_LIST:
+				offset = 8 + 16*len(o.Addrs)
+				optionData = make([]byte, 8, offset)
+				optionData[0] = byte(o.Type)
+				optionData[1] = byte(offset / 8)
+				/* 2 - 7 Reserved */
+				for _, a := range o.Addrs {
+					addr := net.ParseIP(a).To16()
+					if addr == nil {
+						fmt.Println("Bad IP6 Address List Address")
+						return 0, optErr
+					}
+					optionData = append(optionData, addr...)
+				}
+			case OPT_DNSSL:
+				optionData = make([]byte, 8)
+				optionData[0] = OPT_DNSSL
+				binary.BigEndian.PutUint32(optionData[4:8], o.DNSSL_Lifetime)
+				for _, d := range o.DNSSL_Domains {
+					name, err := dnsEncodeName(d)
+					if err != nil {
+						return 0, optErr
+					}
+					optionData = append(optionData, name...)
+				}
+				optionData = padOption(optionData)
+				offset = len(optionData)
+			case OPT_ROUTE_INFORMATION:
+				// only as much of the prefix as Prefix Length needs
+				length := 1
+				if o.PI_Prefix_Len > 64 {
+					length = 3
+				} else if o.PI_Prefix_Len > 0 {
+					length = 2
+				}
+				offset = length * 8
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_ROUTE_INFORMATION
+				optionData[1] = byte(length)
+				optionData[2] = byte(o.PI_Prefix_Len)
+				optionData[3] = o.RIO_Pref & RA_FLAG_PREF_LOW
+				binary.BigEndian.PutUint32(optionData[4:8], o.RIO_Lifetime)
+				if length > 1 {
+					addr := net.ParseIP(o.Addr).To16()
+					if addr == nil {
+						fmt.Println("Bad IP6 Route Prefix Address")
+						return 0, optErr
+					}
+					copy(optionData[8:], addr)
+				}
+			case OPT_RDNS:
+				servers := o.RDNS_Servers
+				if o.RDNS_Server2 != "" {
+					servers = append([]string{o.RDNS_Server2}, servers...)
+				}
+				if o.RDNS_Server1 != "" {
+					servers = append([]string{o.RDNS_Server1}, servers...)
+				}
+				length := 1 + 2*len(servers)
+				if (len(servers) == 0 || length > 255) && !o.LengthOverride {
+					fmt.Println("RDNSS needs 1 to 127 servers")
+					return 0, optErr
+				}
+				offset = length * 8
+				optionData = make([]byte, 8, offset)
+				optionData[0] = OPT_RDNS
+				optionData[1] = byte(length)
+				binary.BigEndian.PutUint16(optionData[2:4], 0)
+				binary.BigEndian.PutUint32(optionData[4:8], o.RDNS_Lifetime)
+
+				for _, srv := range servers {
+					addr := net.ParseIP(srv).To16()
+					if addr == nil {
+						fmt.Println("Bad IP6 RDNS Server Address")
+						return 0, optErr
+					}
+					optionData = append(optionData, addr...)
+				}
+			case OPT_IP_ADDR_PREFIX:
+				var err error
+				optionData, err = o.fmipIPPrefix()
+				if err != nil {
+					return 0, optErr
+				}
+				offset = len(optionData)
+			case OPT_LINKADDR:
+				var err error
+				optionData, err = o.fmipLinkAddr()
+				if err != nil {
+					return 0, optErr
+				}
+				offset = len(optionData)
+			case OPT_CGA:
+				optionData = o.cgaOption()
+				offset = len(optionData)
+			case OPT_RSA_SIGNATURE:
+				var err error
+				optionData, err = o.rsaSignature()
+				if err != nil {
+					return 0, optErr
+				}
+				offset = len(optionData)
+				t.sigOpt = &t.Options[i]
+				t.sigPos = len(t.Data)
+			case OPT_TIMESTAMP:
+				if o.TS_Time.IsZero() {
+					t.Options[i].TS_Time = time.Now()
+					o.TS_Time = t.Options[i].TS_Time
+				}
+				optionData = o.timestamp()
+				offset = len(optionData)
+			case OPT_NONCE:
+				if len(o.Nonce) == 0 {
+					t.Options[i].Nonce = make([]byte, nonceDefaultLen)
+					if _, err := rand.Read(t.Options[i].Nonce); err != nil {
+						fmt.Println("Could not generate Nonce")
+						return 0, optErr
+					}
+					o.Nonce = t.Options[i].Nonce
+				}
+				optionData = o.nonce()
+				offset = len(optionData)
+			case OPT_TRUST_ANCHOR:
+				var err error
+				optionData, err = o.trustAnchor()
+				if err != nil {
+					return 0, optErr
+				}
+				offset = len(optionData)
+			case OPT_CERTIFICATE:
+				optionData = []byte{OPT_CERTIFICATE, 0, byte(o.CERT_Type), 0}
+				optionData = padOption(append(optionData, o.CERT_Data...))
+				offset = len(optionData)
+			case OPT_ADDR_REGISTRATION:
+				rovr := padROVR(o.ARO_ROVR)
+				offset = 8 + len(rovr)
+				optionData = make([]byte, 8, offset)
+				optionData[0] = OPT_ADDR_REGISTRATION
+				optionData[1] = byte(offset / 8)
+				optionData[2] = byte(o.ARO_Status)
+				optionData[3] = byte(o.ARO_Opaque)
+				optionData[4] = byte(o.ARO_Flags)
+				optionData[5] = byte(o.ARO_TID)
+				binary.BigEndian.PutUint16(optionData[6:8], o.ARO_Lifetime)
+				optionData = append(optionData, rovr...)
+			case OPT_6LOWPAN_CONTEXT:
+				offset = coShortLen
+				if o.CO_Len > coShortPrefix {
+					offset = coLongLen
+				}
+				optionData = make([]byte, offset)
+				optionData[0] = OPT_6LOWPAN_CONTEXT
+				optionData[1] = byte(offset / 8)
+				optionData[2] = byte(o.CO_Len)
+				optionData[3] = byte(o.CO_CID) & CO_CID_MASK
+				if o.CO_Compress {
+					optionData[3] |= CO_FLAG_COMPRESS
+				}
+				binary.BigEndian.PutUint16(optionData[6:8], o.CO_Lifetime)
+				addr := net.ParseIP(o.Addr).To16()
+				if addr == nil {
+					fmt.Println("Bad IP6 Context Prefix")
+					return 0, optErr
+				}
+				copy(optionData[8:], addr)
 
+			default:
+				fmt.Println("Unknown option type", o.Type, "needs Raw")
+				return 0, optErr
+			}
+		}
+		if o.LengthOverride && len(optionData) > 1 {
+			optionData[1] = byte(o.Length)
 		}
 		t.Data = append(t.Data, optionData...)
 		t.DataLen += offset
@@ -689,13 +1323,46 @@ func (t *ICMP6) buildOptions() (int, error) {
 	return t.DataLen, nil
 }
 
+// MarshalBinary returns the Ethernet frame built by BuildICMPPacket
+// or decoded by Parse
+func (t *ICMP6) MarshalBinary() ([]byte, error) {
+	if len(t.frame) == 0 {
+		fmt.Println("Must build headers first!")
+		return nil, errors.New("Error marshal frame")
+	}
+	return t.etherFrame(t.frame)
+}
+
+// wrap an IPv6 packet in an Ethernet frame with SrcMAC and DstMAC
+func (t *ICMP6) etherFrame(pkt []byte) (ethernet.Frame, error) {
+	mErr := errors.New("Error marshal frame")
+
+	myFrame := make(ethernet.Frame, EtherLen+len(pkt))
+	copy(myFrame[EtherLen:], pkt)
+
+	srcMac, err := net.ParseMAC(t.SrcMAC)
+	if err != nil {
+		fmt.Println("error parse Source MAC")
+		return nil, mErr
+	}
+	dstMac, err := net.ParseMAC(t.DstMAC)
+	if err != nil {
+		fmt.Println("error parse Destination MAC")
+		return nil, mErr
+	}
+
+	myFrame.Prepare(dstMac, srcMac, ethernet.NotTagged, ethernet.IPv6, len(pkt))
+	return myFrame, nil
+}
+
 // Send ICMP6 Packet
-// Must call BuildICMPPacket to build the frame before sending
+// Must call BuildICMPPacket to build the frame before sending.
+// If FragSize is set the packet is sent as fragments
 func (t *ICMP6) Send() error {
 	aErr := errors.New("Error building attack frame")
 
-	if len(t.frame) == 0 {
-		fmt.Println("Must build headers first!")
+	frames, err := t.sendFrames()
+	if err != nil {
 		return aErr
 	}
 
@@ -711,31 +1378,46 @@ func (t *ICMP6) Send() error {
 		fmt.Println("Error getting interface", err)
 		return aErr
 	}
+	defer myDev.Close()
 
-	myFrame := make(ethernet.Frame, 1500)
-	pktlen := len(t.frame)
-	for i, b := range t.frame {
-		myFrame[EtherLen+i] = b
+	if err = t.writeFrames(myDev, frames); err != nil {
+		return aErr
 	}
+	return nil
+}
 
-	srcMac, err := net.ParseMAC(t.SrcMAC)
-	if err != nil {
-		fmt.Println("error parse Source MAC")
-		return aErr
+// the frames Send writes. These are the fragments if FragSize is set
+func (t *ICMP6) sendFrames() ([]ethernet.Frame, error) {
+	if t.FragSize > 0 {
+		frags, err := t.Fragments()
+		if err != nil {
+			return nil, err
+		}
+		return t.fragmentFrames(frags)
 	}
-	dstMac, err := net.ParseMAC(t.DstMAC)
+	myFrame, err := t.MarshalBinary()
 	if err != nil {
-		fmt.Println("error parse Destination MAC")
-		return aErr
+		return nil, err
 	}
+	return []ethernet.Frame{myFrame}, nil
+}
 
-	myFrame.Prepare(dstMac, srcMac, ethernet.NotTagged, ethernet.IPv6, pktlen)
-	err = myDev.Write(myFrame)
-	if err != nil {
-		fmt.Println("Error write frame")
-		return aErr
+// write frames on dev and record each one in Capture
+func (t *ICMP6) writeFrames(dev ether.Dev, frames []ethernet.Frame) error {
+	wErr := errors.New("Error write frame")
+
+	for _, myFrame := range frames {
+		if err := dev.Write(myFrame); err != nil {
+			fmt.Println("Error write frame")
+			return wErr
+		}
+		if t.Capture != nil {
+			if err := t.Capture.WriteFrame(time.Now(), myFrame); err != nil {
+				fmt.Println(err)
+				return wErr
+			}
+		}
 	}
-	myDev.Close()
 	return nil
 }
 
@@ -785,6 +1467,10 @@ type icmp6Header struct {
 	Payload    []byte
 	Src        net.IP // for psdhdr
 	Dst        net.IP
+
+	// header data bytes left off messages shorter
+	// than ICMPHeaderLen (ie MRD Solicitation, MPL or RPL Control)
+	Short int
 }
 
 // Marshal returns the binary encoding of h.
@@ -792,47 +1478,60 @@ func (h *icmp6Header) marshal() ([]byte, error) {
 	if h == nil {
 		return nil, syscall.EINVAL
 	}
-	b := make([]byte, ICMPHeaderLen+h.PayloadLen)
+	hl := ICMPHeaderLen - h.Short
+	b := make([]byte, hl+h.PayloadLen)
 	/* type */
 	b[0] = byte(h.Type)
 	b[1] = byte(h.Code)
 	b[2] = 0 // checksum 0 before calc
 	b[3] = 0
 	//fmt.Println("h.PayloadLen:", h.PayloadLen)
-	copy(b[4:8], h.Data[:4])
+	copy(b[4:hl], h.Data[:4])
 
 	// debug
 	//fmt.Println("buff data ", b[4:8], h.Data[:4])
 
-	copy(b[8:8+h.PayloadLen], h.Payload[:h.PayloadLen])
+	copy(b[hl:hl+h.PayloadLen], h.Payload[:h.PayloadLen])
 
-	/* pseudo header for checksum */
-	p := make([]byte, 40+ICMPHeaderLen+h.PayloadLen)
-	if ip := h.Src.To16(); ip != nil {
+	if h.Dst.To16() == nil {
+		return nil, syscall.EINVAL // need to handle correctly
+	}
+	cs := pseudoCsum(h.Src, h.Dst, syscall.IPPROTO_ICMPV6, b)
+	b[2] = byte(cs)
+	b[3] = byte(cs >> 8)
+	return b, nil
+}
+
+// checksum of an upper layer message with the IPv6 pseudo header.
+// The checksum field of msg must be zero
+func pseudoCsum(src, dst net.IP, nh int, msg []byte) uint16 {
+	/* pseudo header for checksum, padded to 16 bit boundry */
+	p := make([]byte, 40+len(msg)+len(msg)%2)
+	if ip := src.To16(); ip != nil {
 		copy(p[0:16], ip[:net.IPv6len])
 	}
-	if ip := h.Dst.To16(); ip != nil {
+	if ip := dst.To16(); ip != nil {
 		copy(p[16:32], ip[:net.IPv6len])
-	} else {
-		return nil, syscall.EINVAL // need to handle correctly
 	}
-
-	p[32] = 0
-	p[33] = 0
-	p[34] = byte((ICMPHeaderLen + h.PayloadLen) / 256)
-	p[35] = byte((ICMPHeaderLen + h.PayloadLen) % 256)
+	binary.BigEndian.PutUint32(p[32:36], uint32(len(msg)))
 	p[36] = 0
 	p[37] = 0
 	p[38] = 0
-	p[39] = syscall.IPPROTO_ICMPV6
+	p[39] = byte(nh)
 
-	copy(p[40:40+ICMPHeaderLen], b[0:ICMPHeaderLen])
-	copy(p[40+ICMPHeaderLen:40+ICMPHeaderLen+h.PayloadLen], b[ICMPHeaderLen:ICMPHeaderLen+h.PayloadLen])
+	copy(p[40:], msg)
+	return csum(p)
+}
 
-	cs := csum(p)
-	b[2] = byte(cs)
-	b[3] = byte(cs >> 8)
-	return b, nil
+// copy a type specific body to the start of the payload.
+// Returns the offset for the Data that follows
+func (h *icmp6Header) setBody(body []byte) int {
+	if len(body) > len(h.Payload) {
+		h.Payload = append(h.Payload, make([]byte, len(body)-len(h.Payload))...)
+	}
+	copy(h.Payload, body)
+	h.PayloadLen += len(body)
+	return len(body)
 }
 
 func csum(b []byte) uint16 {
@@ -0,0 +1,110 @@
+package hi6
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseRouteInformation(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_ROUTE_INFORMATION, PI_Prefix_Len: 0, RIO_Pref: RA_FLAG_PREF_LOW, RIO_Lifetime: 10})
+	p.AddOption(Option{Type: OPT_ROUTE_INFORMATION, PI_Prefix_Len: 48, RIO_Pref: RA_FLAG_PREF_HIGH,
+		RIO_Lifetime: 0xffffffff, Addr: "2001:db8:1::"})
+	p.AddOption(Option{Type: OPT_ROUTE_INFORMATION, PI_Prefix_Len: 96, Addr: "2001:db8:1:2:3:4::"})
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 3 {
+		t.Fatalf("got %d options", len(q.Options))
+	}
+	for i, o := range q.Options {
+		w := p.Options[i]
+		if w.Addr == "" {
+			w.Addr = "::"
+		}
+		if o.PI_Prefix_Len != w.PI_Prefix_Len || o.RIO_Pref != w.RIO_Pref || o.RIO_Lifetime != w.RIO_Lifetime || o.Addr != w.Addr {
+			t.Errorf("option %d %+v", i, o)
+		}
+	}
+	// only as much prefix as the Prefix Length needs. The options
+	// follow the 8 bytes of Reachable and Retransmit Timer
+	if n := len(q.frame) - IPHeaderLen - ICMPHeaderLen - 8; n != 8+16+24 {
+		t.Fatalf("options are %d bytes", n)
+	}
+}
+
+func TestParseRDNSS(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_RDNS, RDNS_Lifetime: 600, RDNS_Server1: "2001:db8::53",
+		RDNS_Servers: []string{"2001:db8::54", "2001:db8::55"}})
+	q := roundTrip(t, &p)
+
+	o := q.Options[0]
+	if o.RDNS_Lifetime != 600 || !reflect.DeepEqual(o.RDNS_Servers, []string{"2001:db8::53", "2001:db8::54", "2001:db8::55"}) {
+		t.Fatalf("RDNSS %+v", o)
+	}
+	if o.RDNS_Server1 != "" || o.RDNS_Server2 != "" {
+		t.Fatalf("servers also in RDNS_Server1/2 %+v", o)
+	}
+
+	// the parsed option builds the same frame again
+	want, _ := p.MarshalBinary()
+	q.Iface = "lo"
+	if err := q.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := q.MarshalBinary()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("rebuilt\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestRDNSSServerCount(t *testing.T) {
+	many := make([]string, 128)
+	for i := range many {
+		many[i] = "2001:db8::53"
+	}
+	for _, servers := range [][]string{nil, many} {
+		p := ICMP6{Iface: "lo", DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+		p.AddOption(Option{Type: OPT_RDNS, RDNS_Servers: servers})
+		if err := p.BuildICMPPacket(); err == nil {
+			t.Errorf("expected error for %d servers", len(servers))
+		}
+	}
+
+	// unless the Length is forged
+	p := ICMP6{Iface: "lo", DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_RDNS, LengthOverride: true, Length: 0})
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.MarshalBinary()
+	opt := b[EtherLen+IPHeaderLen+ICMPHeaderLen+8:]
+	if len(opt) != 8 || opt[0] != OPT_RDNS || opt[1] != 0 {
+		t.Fatalf("option % x", opt)
+	}
+}
+
+func TestOptionRaw(t *testing.T) {
+	raw := []byte{OPT_MTU, 2, 0, 0, 0, 0, 5, 220, 1, 2, 3, 4, 5, 6, 7, 8}
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_MTU, MTU: 1280, Raw: raw})
+	p.AddOption(Option{Type: 200, Raw: []byte{200, 1, 0, 0, 0, 0, 0, 0}})
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 2 || q.Options[0].MTU != 1500 || q.Options[1].Type != 200 {
+		t.Fatalf("%+v", q.Options)
+	}
+	b, _ := p.MarshalBinary()
+	if !bytes.Equal(b[EtherLen+IPHeaderLen+ICMPHeaderLen+8:][:16], raw) {
+		t.Fatal("Raw not sent as is")
+	}
+}
+
+func TestOptionUnknownType(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: 200})
+	if err := p.BuildICMPPacket(); err == nil {
+		t.Fatal("expected error for unknown option type without Raw")
+	}
+}
@@ -0,0 +1,73 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ILNPv6 Locator Update Operations
+const (
+	ILNP_ADVERTISEMENT   = 1
+	ILNP_ACKNOWLEDGEMENT = 2
+)
+
+// ILNPv6 lengths
+const (
+	ilnpLocatorLen  = 8
+	ilnpLocEntryLen = 12
+)
+
+// ILNPLocator is a Locator of an ILNPv6 Locator Update
+type ILNPLocator struct {
+	// 64 bit Locator given as an IPv6 prefix (ie 2001:db8:1:2::)
+	Locator string
+
+	// Preference and Lifetime in seconds
+	Preference uint16
+	Lifetime   uint16
+}
+
+// Convenience function to add ILNPv6 Locators to ICMP6 struct
+func (t *ICMP6) AddILNPLocator(l ILNPLocator) {
+	t.ILNP_Locators = append(t.ILNP_Locators, l)
+}
+
+// ILNPv6 Locator Update Locators after the ICMP6 header
+func (t *ICMP6) ilnpBody() ([]byte, error) {
+	lErr := errors.New("Error building ILNPv6 Locator Update")
+
+	var b []byte
+	for _, l := range t.ILNP_Locators {
+		ip := net.ParseIP(l.Locator)
+		if ip == nil {
+			fmt.Println("ILNPv6: could not parse locator", l.Locator)
+			return nil, lErr
+		}
+		e := make([]byte, ilnpLocEntryLen)
+		copy(e[0:ilnpLocatorLen], ip.To16())
+		binary.BigEndian.PutUint16(e[8:10], l.Preference)
+		binary.BigEndian.PutUint16(e[10:12], l.Lifetime)
+		b = append(b, e...)
+	}
+	return b, nil
+}
+
+// decode an ILNPv6 Locator Update. data is the ICMP6 header
+// data and body everything after it
+func (t *ICMP6) parseILNP(data []byte, body []byte) []byte {
+	n := int(data[0])
+	t.ILNP_Operation = int(data[1])
+	for i := 0; i < n && len(body) >= ilnpLocEntryLen; i++ {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, body[0:ilnpLocatorLen])
+		t.AddILNPLocator(ILNPLocator{
+			Locator:    ip.String(),
+			Preference: binary.BigEndian.Uint16(body[8:10]),
+			Lifetime:   binary.BigEndian.Uint16(body[10:12]),
+		})
+		body = body[ilnpLocEntryLen:]
+	}
+	return body
+}
@@ -0,0 +1,23 @@
+package hi6
+
+import "testing"
+
+func TestParseILNPLocatorUpdate(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeILNPv6LocatorUpdate, ILNP_Operation: ILNP_ADVERTISEMENT}
+	p.AddILNPLocator(ILNPLocator{Locator: "2001:db8:1:2::", Preference: 1, Lifetime: 600})
+	p.AddILNPLocator(ILNPLocator{Locator: "2001:db8:3:4::", Preference: 2, Lifetime: 60})
+	q := roundTrip(t, &p)
+
+	if q.ILNP_Operation != ILNP_ADVERTISEMENT || len(q.ILNP_Locators) != 2 {
+		t.Fatalf("%+v", q)
+	}
+	for i, l := range q.ILNP_Locators {
+		if l != p.ILNP_Locators[i] {
+			t.Errorf("locator %d %+v", i, l)
+		}
+	}
+	if len(q.Data) != 0 {
+		t.Fatalf("Data % x", q.Data)
+	}
+}
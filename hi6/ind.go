@@ -0,0 +1,28 @@
+package hi6
+
+import (
+	"fmt"
+)
+
+// Inverse Neighbor Discovery messages must carry Source and Target
+// Link-Layer Address options, and Advertisements a Target Address
+// List. Missing options are only reported so broken messages can
+// still be sent
+func (t *ICMP6) checkINDOptions() {
+	required := []int{OPT_SOURCE_LINKADDR, OPT_TARGET_LINKADDR}
+	if t.Type == ICMPTypeInverseNeighborDiscoveryAdvertisement {
+		required = append(required, OPT_TARGET_ADDR_LIST)
+	}
+	for _, r := range required {
+		found := false
+		for _, o := range t.Options {
+			if o.Type == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Println("IND: missing required option type", r)
+		}
+	}
+}
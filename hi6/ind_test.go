@@ -0,0 +1,33 @@
+package hi6
+
+import "testing"
+
+func TestParseInverseNDAdvertisement(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeInverseNeighborDiscoveryAdvertisement}
+	p.AddOption(Option{Type: OPT_SOURCE_LINKADDR, Addr: "10:0b:a9:aa:aa:aa"})
+	p.AddOption(Option{Type: OPT_TARGET_LINKADDR, Addr: "10:0b:a9:bb:bb:bb"})
+	p.AddOption(Option{Type: OPT_TARGET_ADDR_LIST, Addrs: []string{"fe80::1", "2001:db8::1", "2001:db8::2"}})
+	q := roundTrip(t, &p)
+
+	if q.Type != ICMPTypeInverseNeighborDiscoveryAdvertisement || len(q.Options) != 3 {
+		t.Fatalf("%+v", q)
+	}
+	al := q.Options[2]
+	if al.Type != OPT_TARGET_ADDR_LIST || len(al.Addrs) != 3 || al.Addrs[0] != "fe80::1" || al.Addrs[2] != "2001:db8::2" {
+		t.Fatalf("address list %+v", al)
+	}
+}
+
+func TestParseInverseNDSolicitation(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeInverseNeighborDiscoverySolicitation}
+	p.AddOption(Option{Type: OPT_SOURCE_LINKADDR, Addr: "10:0b:a9:aa:aa:aa"})
+	p.AddOption(Option{Type: OPT_TARGET_LINKADDR, Addr: "10:0b:a9:bb:bb:bb"})
+	p.AddOption(Option{Type: OPT_SOURCE_ADDR_LIST, Addrs: []string{"fe80::1"}})
+	p.AddOption(Option{Type: OPT_MTU, MTU: 1500})
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 4 || q.Options[1].Addr != "10:0b:a9:bb:bb:bb" ||
+		len(q.Options[2].Addrs) != 1 || q.Options[2].Addrs[0] != "fe80::1" || q.Options[3].MTU != 1500 {
+		t.Fatalf("%+v", q.Options)
+	}
+}
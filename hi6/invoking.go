@@ -0,0 +1,291 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ICMP6 Destination Unreachable Codes
+const (
+	DU_NO_ROUTE         = 0
+	DU_ADMIN_PROHIBITED = 1
+	DU_BEYOND_SCOPE     = 2
+	DU_ADDR_UNREACHABLE = 3
+	DU_PORT_UNREACHABLE = 4
+	DU_SRC_POLICY       = 5
+	DU_REJECT_ROUTE     = 6
+)
+
+// ICMP6 Time Exceeded Codes
+const (
+	TE_HOPLIMIT   = 0
+	TE_REASSEMBLY = 1
+)
+
+// ICMP6 Parameter Problem Codes
+const (
+	PP_HEADER_FIELD          = 0
+	PP_NEXT_HEADER           = 1
+	PP_OPTION                = 2
+	PP_FIRST_FRAG_INCOMPLETE = 3 /* RFC 7112 */
+)
+
+// TCP Flags for the invoking packet
+const (
+	TCP_FLAG_FIN = 0x01
+	TCP_FLAG_SYN = 0x02
+	TCP_FLAG_RST = 0x04
+	TCP_FLAG_PSH = 0x08
+	TCP_FLAG_ACK = 0x10
+	TCP_FLAG_URG = 0x20
+)
+
+// Header lengths of the invoking packet
+const (
+	TCPHeaderLen = 20
+	UDPHeaderLen = 8
+)
+
+// An ICMP6 error message must fit in the IPv6 minimum MTU
+const MinMTU = 1280
+
+// InvokingPacket builds the IPv6 packet embedded in ICMP6 error
+// messages (Destination Unreachable, Packet Too Big, Time Exceeded
+// and Parameter Problem)
+type InvokingPacket struct {
+	// Source and Destination IP6 Address of the invoking packet
+	SrcIP string
+	DstIP string
+
+	// IPv6 Hop Limit. If 0 uses 64
+	HopLimit int
+
+	// Set to true to use HopLimit as is, even 0. Parse sets it
+	HopLimitOverride bool
+
+	// IPv6 Flow Label
+	FlowLabel int
+
+	// Extension Headers of the invoking packet
+	ExtHeaders []ExtHeader
+
+	// Upper layer protocol. syscall.IPPROTO_TCP and syscall.IPPROTO_UDP
+	// get a header built from the fields below. For anything else
+	// Payload is used as the whole upper layer message
+	Proto int
+
+	// TCP/UDP Ports
+	SrcPort int
+	DstPort int
+
+	// TCP Sequence and Acknowledgment Number, Flags and Window.
+	// If Window is 0 uses 65535
+	Seq      uint32
+	Ack      uint32
+	TCPFlags int
+	Window   int
+
+	// Upper layer payload
+	Payload []byte
+}
+
+// Marshal returns the invoking packet with correct
+// lengths and upper layer checksum
+func (ip *InvokingPacket) Marshal() ([]byte, error) {
+	iErr := errors.New("Error building invoking packet")
+
+	src := net.ParseIP(ip.SrcIP)
+	dst := net.ParseIP(ip.DstIP)
+	if src == nil || dst == nil {
+		fmt.Println("Invoking: could not parse IP addresses")
+		return nil, iErr
+	}
+
+	var ul []byte
+	switch ip.Proto {
+	case syscall.IPPROTO_TCP:
+		ul = make([]byte, TCPHeaderLen+len(ip.Payload))
+		binary.BigEndian.PutUint16(ul[0:2], uint16(ip.SrcPort))
+		binary.BigEndian.PutUint16(ul[2:4], uint16(ip.DstPort))
+		binary.BigEndian.PutUint32(ul[4:8], ip.Seq)
+		binary.BigEndian.PutUint32(ul[8:12], ip.Ack)
+		ul[12] = (TCPHeaderLen / 4) << 4
+		ul[13] = byte(ip.TCPFlags)
+		win := ip.Window
+		if win == 0 {
+			win = 65535
+		}
+		binary.BigEndian.PutUint16(ul[14:16], uint16(win))
+		copy(ul[TCPHeaderLen:], ip.Payload)
+	case syscall.IPPROTO_UDP:
+		ul = make([]byte, UDPHeaderLen+len(ip.Payload))
+		binary.BigEndian.PutUint16(ul[0:2], uint16(ip.SrcPort))
+		binary.BigEndian.PutUint16(ul[2:4], uint16(ip.DstPort))
+		binary.BigEndian.PutUint16(ul[4:6], uint16(len(ul)))
+		copy(ul[UDPHeaderLen:], ip.Payload)
+	default:
+		ul = append(ul, ip.Payload...)
+	}
+
+	ext, nh, err := marshalExtHeaders(ip.ExtHeaders, ip.Proto)
+	if err != nil {
+		return nil, iErr
+	}
+
+	// upper layer checksum
+	cdst := finalDst(ip.ExtHeaders, dst)
+	switch ip.Proto {
+	case syscall.IPPROTO_TCP:
+		cs := pseudoCsum(src, cdst, ip.Proto, ul)
+		ul[16] = byte(cs)
+		ul[17] = byte(cs >> 8)
+	case syscall.IPPROTO_UDP:
+		cs := pseudoCsum(src, cdst, ip.Proto, ul)
+		if cs == 0 {
+			cs = 0xffff
+		}
+		ul[6] = byte(cs)
+		ul[7] = byte(cs >> 8)
+	}
+
+	h := ip6Header{
+		Version:    6,
+		FlowLabel:  ip.FlowLabel,
+		PayloadLen: len(ext) + len(ul),
+		NextHeader: nh,
+		HopLimit:   64,
+		Src:        src,
+		Dst:        dst,
+	}
+	if ip.HopLimit != 0 || ip.HopLimitOverride {
+		h.HopLimit = ip.HopLimit
+	}
+	b, err := h.marshal()
+	if err != nil {
+		return nil, iErr
+	}
+	b = append(b, ext...)
+	return append(b, ul...), nil
+}
+
+// error messages carry as much of the invoking packet as
+// fits in MinMTU. extLen is the length of our own extension headers
+func (t *ICMP6) invokingPacket(extLen int) ([]byte, error) {
+	inv := t.InvokingData
+	if t.Invoking != nil {
+		var err error
+		inv, err = t.Invoking.Marshal()
+		if err != nil {
+			return nil, err
+		}
+	}
+	max := MinMTU - IPHeaderLen - extLen - ICMPHeaderLen
+	if max < 0 {
+		max = 0
+	}
+	if len(inv) > max {
+		inv = inv[:max]
+	}
+	return inv, nil
+}
+
+// ICMP6 error messages
+func isErrorMessage(typ ICMPType) bool {
+	return typ < ICMPTypeEchoRequest
+}
+
+// decode the (possibly truncated) invoking packet of an error message
+func parseInvoking(b []byte) *InvokingPacket {
+	if len(b) < IPHeaderLen || b[0]>>4 != 6 {
+		return nil
+	}
+	ip := &InvokingPacket{
+		SrcIP:            net.IP(b[8:24]).String(),
+		DstIP:            net.IP(b[24:40]).String(),
+		HopLimit:         int(b[7]),
+		HopLimitOverride: true,
+		FlowLabel:        int(binary.BigEndian.Uint32(b[0:4]) & 0xfffff),
+	}
+	nh, off, exts, err := walkExtHeaders(b)
+	if err != nil {
+		return ip
+	}
+	ip.ExtHeaders = exts
+	ip.Proto = nh
+	ul := b[off:]
+
+	switch nh {
+	case syscall.IPPROTO_TCP:
+		if len(ul) < TCPHeaderLen {
+			return ip
+		}
+		ip.SrcPort = int(binary.BigEndian.Uint16(ul[0:2]))
+		ip.DstPort = int(binary.BigEndian.Uint16(ul[2:4]))
+		ip.Seq = binary.BigEndian.Uint32(ul[4:8])
+		ip.Ack = binary.BigEndian.Uint32(ul[8:12])
+		ip.TCPFlags = int(ul[13])
+		ip.Window = int(binary.BigEndian.Uint16(ul[14:16]))
+		hl := int(ul[12]>>4) * 4
+		if hl >= TCPHeaderLen && hl <= len(ul) {
+			ip.Payload = append([]byte(nil), ul[hl:]...)
+		}
+	case syscall.IPPROTO_UDP:
+		if len(ul) < UDPHeaderLen {
+			return ip
+		}
+		ip.SrcPort = int(binary.BigEndian.Uint16(ul[0:2]))
+		ip.DstPort = int(binary.BigEndian.Uint16(ul[2:4]))
+		ip.Payload = append([]byte(nil), ul[UDPHeaderLen:]...)
+	default:
+		ip.Payload = append([]byte(nil), ul...)
+	}
+	return ip
+}
+
+// SetPacketTooBig turns t into a Packet Too Big for the victim flow.
+// flow is the packet the victim would have sent (victim is SrcIP),
+// so the message is sent to flow.SrcIP unless DstIP is already set.
+// The forged packet is padded to look larger than mtu, and mtu may
+// be below MinMTU to test atomic fragments. TCP flows default to
+// ACK|PSH. Set SrcIP to a router on the path and build as usual
+func (t *ICMP6) SetPacketTooBig(mtu uint32, flow InvokingPacket) error {
+	pErr := errors.New("Error forging Packet Too Big")
+
+	ext, _, err := marshalExtHeaders(flow.ExtHeaders, flow.Proto)
+	if err != nil {
+		return pErr
+	}
+
+	// the victim must have sent more than mtu
+	size := 1500
+	if int(mtu) >= size {
+		size = int(mtu) + 1
+	}
+	hdr := IPHeaderLen + len(ext)
+	switch flow.Proto {
+	case syscall.IPPROTO_TCP:
+		hdr += TCPHeaderLen
+		if flow.TCPFlags == 0 {
+			flow.TCPFlags = TCP_FLAG_ACK | TCP_FLAG_PSH
+		}
+	case syscall.IPPROTO_UDP:
+		hdr += UDPHeaderLen
+	}
+	if hdr+len(flow.Payload) < size {
+		pad := make([]byte, size-hdr)
+		copy(pad, flow.Payload)
+		flow.Payload = pad
+	}
+
+	t.Type = ICMPTypePacketTooBig
+	t.Code = 0
+	t.ICMP6_mtu = mtu
+	if t.DstIP == "" {
+		t.DstIP = flow.SrcIP
+	}
+	t.Invoking = &flow
+	return nil
+}
@@ -0,0 +1,81 @@
+package hi6
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+)
+
+func TestParseDestinationUnreachable(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeDestinationUnreachable, Code: DU_PORT_UNREACHABLE}
+	p.Invoking = &InvokingPacket{SrcIP: "2001:db8::1", DstIP: "2001:db8::3", HopLimitOverride: true,
+		Proto: syscall.IPPROTO_UDP, SrcPort: 5353, DstPort: 53, Payload: []byte("query")}
+	q := roundTrip(t, &p)
+
+	inv := q.Invoking
+	if inv == nil {
+		t.Fatal("no invoking packet")
+	}
+	if inv.SrcIP != "2001:db8::1" || inv.DstIP != "2001:db8::3" || inv.HopLimit != 0 || inv.Proto != syscall.IPPROTO_UDP ||
+		inv.SrcPort != 5353 || inv.DstPort != 53 || !bytes.Equal(inv.Payload, []byte("query")) {
+		t.Fatalf("%+v", inv)
+	}
+	if len(q.Data) != 0 {
+		t.Fatalf("invoking packet also in Data: % x", q.Data)
+	}
+
+	// rebuilding the parsed packet gives the same frame
+	want, _ := p.MarshalBinary()
+	q.Iface = "lo"
+	if err := q.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := q.MarshalBinary()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("rebuilt\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestInvokingTruncated(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeTimeExceeded, Code: TE_HOPLIMIT}
+	p.Invoking = &InvokingPacket{SrcIP: "2001:db8::1", DstIP: "2001:db8::3", Proto: syscall.IPPROTO_TCP,
+		SrcPort: 40000, DstPort: 443, TCPFlags: TCP_FLAG_SYN, Payload: make([]byte, 2000)}
+	q := roundTrip(t, &p)
+
+	b, _ := p.MarshalBinary()
+	if len(b)-EtherLen != MinMTU {
+		t.Fatalf("packet is %d bytes", len(b)-EtherLen)
+	}
+	inv := q.Invoking
+	if inv == nil || inv.DstPort != 443 || inv.TCPFlags != TCP_FLAG_SYN || inv.HopLimit != 64 {
+		t.Fatalf("%+v", inv)
+	}
+}
+
+func TestSetPacketTooBig(t *testing.T) {
+	p := ICMP6{Iface: "lo", SrcIP: "2001:db8::fe", DstMAC: "00:11:22:33:44:55"}
+	flow := InvokingPacket{SrcIP: "2001:db8::1", DstIP: "2001:db8::2", Proto: syscall.IPPROTO_TCP,
+		SrcPort: 443, DstPort: 40000}
+	if err := p.SetPacketTooBig(1280, flow); err != nil {
+		t.Fatal(err)
+	}
+	if p.DstIP != "2001:db8::1" {
+		t.Fatalf("sent to %s", p.DstIP)
+	}
+	q := roundTrip(t, &p)
+
+	if q.Type != ICMPTypePacketTooBig || q.ICMP6_mtu != 1280 {
+		t.Fatalf("%+v", q)
+	}
+	inv := q.Invoking
+	if inv == nil || inv.SrcPort != 443 || inv.TCPFlags != TCP_FLAG_ACK|TCP_FLAG_PSH {
+		t.Fatalf("%+v", inv)
+	}
+	// the invoking packet claims more than the mtu
+	b, _ := p.Invoking.Marshal()
+	if len(b) <= 1280 {
+		t.Fatalf("invoking packet is %d bytes", len(b))
+	}
+}
@@ -0,0 +1,193 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/songgao/ether"
+	"github.com/songgao/packets/ethernet"
+	"net"
+	"sync"
+)
+
+// Listener keeps a device open on an interface, decodes the
+// ICMP6 packets it receives and hands them out on a channel
+// or to a Handler
+type Listener struct {
+	// Interface to listen on
+	Iface string
+
+	// Only deliver these ICMP Types. If empty deliver all types
+	Types []ICMPType
+
+	// Only deliver packets from this Source IP6 Address
+	SrcIP string
+
+	// Only deliver packets with this Target Address
+	// (Neighbor Solicitation/Advertisement, Redirect)
+	TargetAddr string
+
+	// If set, Handler is called from the read goroutine for every
+	// packet instead of delivering it on the channel. Handler must
+	// not call Close, Close waits for the read goroutine
+	Handler func(t *ICMP6)
+
+	// internal
+	dev       ether.Dev
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce *sync.Once
+}
+
+// Listen opens the interface and starts reading frames in a goroutine.
+// Matching packets are sent on the returned channel, which is closed
+// when the Listener stops
+func (l *Listener) Listen() (<-chan *ICMP6, error) {
+	lErr := errors.New("Error starting listener")
+
+	if l.dev != nil {
+		fmt.Println("Listener already started")
+		return nil, lErr
+	}
+
+	hwIface, err := net.InterfaceByName(l.Iface)
+	if err != nil {
+		fmt.Println(err)
+		return nil, lErr
+	}
+
+	// only IPv6 frames are worth decoding
+	ff := func(frame ethernet.Frame) bool {
+		return isIP6Frame(frame)
+	}
+	dev, err := ether.NewDev(hwIface, ff)
+	if err != nil {
+		fmt.Println("Error getting interface", err)
+		return nil, lErr
+	}
+	return l.start(dev), nil
+}
+
+// start reading from dev. Every Listen gets its own done
+// channel so a Listener can be closed and started again
+func (l *Listener) start(dev ether.Dev) <-chan *ICMP6 {
+	l.dev = dev
+	l.done = make(chan struct{})
+	l.stopped = make(chan struct{})
+	l.closeOnce = new(sync.Once)
+
+	c := make(chan *ICMP6, 64)
+	go l.read(dev, l.done, l.stopped, c)
+	return c
+}
+
+// an untagged or 802.1Q tagged Ethernet frame carrying IPv6
+func isIP6Frame(frame []byte) bool {
+	if len(frame) <= EtherLen {
+		return false
+	}
+	etype := binary.BigEndian.Uint16(frame[12:14])
+	if etype == 0x8100 && len(frame) > EtherLen+4 {
+		etype = binary.BigEndian.Uint16(frame[16:18])
+	}
+	return etype == 0x86dd
+}
+
+// Close stops the Listener, closes the device and waits for the
+// read goroutine to exit, so Handler is not called after Close
+// returns. Calling Close more than once is fine
+func (l *Listener) Close() error {
+	if l.dev == nil {
+		return nil
+	}
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.dev.Close()
+		<-l.stopped
+		l.dev = nil
+	})
+	return err
+}
+
+// Send writes a built ICMP6 frame on the Listener's device,
+// so replies can be sent without opening the interface again.
+// Like ICMP6.Send the packet is fragmented if FragSize is set
+func (l *Listener) Send(t *ICMP6) error {
+	sErr := errors.New("Error sending on listener")
+
+	if l.dev == nil {
+		fmt.Println("Listener not started")
+		return sErr
+	}
+	frames, err := t.sendFrames()
+	if err != nil {
+		return sErr
+	}
+	if err = t.writeFrames(l.dev, frames); err != nil {
+		return sErr
+	}
+	return nil
+}
+
+// read loop
+func (l *Listener) read(dev ether.Dev, done <-chan struct{}, stopped chan<- struct{}, c chan<- *ICMP6) {
+	defer close(stopped)
+	defer close(c)
+
+	buf := make(ethernet.Frame, 65536)
+	for {
+		buf = buf[:cap(buf)]
+		if err := dev.Read(&buf); err != nil {
+			select {
+			case <-done:
+			default:
+				fmt.Println("Listener read error", err)
+			}
+			return
+		}
+
+		// Parse copies what it keeps so buf can be reused
+		t := new(ICMP6)
+		if err := t.unmarshal(buf); err != nil {
+			continue
+		}
+		if !l.match(t) {
+			continue
+		}
+		t.Iface = l.Iface
+
+		if l.Handler != nil {
+			l.Handler(t)
+			continue
+		}
+		select {
+		case c <- t:
+		case <-done:
+			return
+		}
+	}
+}
+
+// check the packet against the filters
+func (l *Listener) match(t *ICMP6) bool {
+	if len(l.Types) > 0 {
+		found := false
+		for _, typ := range l.Types {
+			if typ == t.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if l.SrcIP != "" && !sameIP(l.SrcIP, t.SrcIP) {
+		return false
+	}
+	if l.TargetAddr != "" && !sameIP(l.TargetAddr, t.TargetAddr) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package hi6
+
+import (
+	"errors"
+	"github.com/songgao/packets/ethernet"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDev hands out frames from in and keeps what is written
+type fakeDev struct {
+	in     chan ethernet.Frame
+	closed chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	out    []ethernet.Frame
+}
+
+func newFakeDev() *fakeDev {
+	return &fakeDev{in: make(chan ethernet.Frame, 8), closed: make(chan struct{})}
+}
+
+func (d *fakeDev) Read(to *ethernet.Frame) error {
+	select {
+	case f := <-d.in:
+		*to = append((*to)[:0], f...)
+		return nil
+	case <-d.closed:
+		return errors.New("closed")
+	}
+}
+
+func (d *fakeDev) Write(f ethernet.Frame) error {
+	d.mu.Lock()
+	d.out = append(d.out, append(ethernet.Frame(nil), f...))
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *fakeDev) Close() error {
+	d.once.Do(func() { close(d.closed) })
+	return nil
+}
+
+func (d *fakeDev) Name() string              { return "fake" }
+func (d *fakeDev) Interface() *net.Interface { return nil }
+
+func echoFrame(t *testing.T, typ ICMPType, src string) ethernet.Frame {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: src, DstMAC: "00:11:22:33:44:55", Type: typ, ICMP6_id: 1}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.MarshalBinary()
+	return b
+}
+
+func TestListenerFilters(t *testing.T) {
+	l := Listener{Iface: "lo", Types: []ICMPType{ICMPTypeEchoReply}, SrcIP: "2001:db8::2"}
+	dev := newFakeDev()
+	c := l.start(dev)
+
+	dev.in <- echoFrame(t, ICMPTypeEchoRequest, "2001:db8::2")
+	dev.in <- echoFrame(t, ICMPTypeEchoReply, "2001:db8::3")
+	dev.in <- echoFrame(t, ICMPTypeEchoReply, "2001:db8:0::2")
+	q := <-c
+	if q.Type != ICMPTypeEchoReply || q.SrcIP != "2001:db8::2" || q.Iface != "lo" {
+		t.Fatalf("%+v", q)
+	}
+
+	l.Close()
+	l.Close()
+	if _, ok := <-c; ok {
+		t.Fatal("channel still open")
+	}
+}
+
+func TestListenerRestart(t *testing.T) {
+	l := Listener{Iface: "lo"}
+	for i := 0; i < 2; i++ {
+		dev := newFakeDev()
+		c := l.start(dev)
+		dev.in <- echoFrame(t, ICMPTypeEchoRequest, "2001:db8::2")
+		if q, ok := <-c; !ok || q.Type != ICMPTypeEchoRequest {
+			t.Fatalf("listen %d: %v", i, q)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListenerSendFragments(t *testing.T) {
+	l := Listener{Iface: "lo"}
+	dev := newFakeDev()
+	l.start(dev)
+	defer l.Close()
+
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoReply, Data: make([]byte, 200), DataLen: 200, FragSize: 64}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Send(&p); err != nil {
+		t.Fatal(err)
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if len(dev.out) != 4 {
+		t.Fatalf("sent %d frames", len(dev.out))
+	}
+	for _, f := range dev.out {
+		if f[EtherLen+6] != EXT_FRAGMENT {
+			t.Fatalf("not a fragment % x", f[:EtherLen+IPHeaderLen])
+		}
+	}
+}
+
+func TestIsIP6Frame(t *testing.T) {
+	f := echoFrame(t, ICMPTypeEchoRequest, "2001:db8::2")
+	if !isIP6Frame(f) {
+		t.Fatal("untagged IPv6 frame dropped")
+	}
+
+	// insert an 802.1Q tag for VLAN 5
+	tagged := append(append(append([]byte(nil), f[:12]...), 0x81, 0x00, 0x00, 0x05), f[12:]...)
+	if !isIP6Frame(tagged) {
+		t.Fatal("tagged IPv6 frame dropped")
+	}
+	q, err := Parse(tagged)
+	if err != nil || q.Type != ICMPTypeEchoRequest {
+		t.Fatal("tagged frame does not parse", err)
+	}
+
+	arp := append([]byte(nil), tagged...)
+	arp[16], arp[17] = 0x08, 0x06
+	if isIP6Frame(arp) || isIP6Frame(f[:EtherLen]) {
+		t.Fatal("non IPv6 frame accepted")
+	}
+}
+
+func TestListenerCloseWaits(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	l := Listener{Iface: "lo", Handler: func(q *ICMP6) {
+		close(entered)
+		<-release
+	}}
+	dev := newFakeDev()
+	l.start(dev)
+	dev.in <- echoFrame(t, ICMPTypeEchoRequest, "2001:db8::2")
+	<-entered
+
+	closed := make(chan struct{})
+	go func() {
+		l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while Handler was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+}
@@ -0,0 +1,42 @@
+package hi6
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Mobile Prefix Advertisement Flags
+const (
+	MIP_FLAG_MANAGED = 0x80
+	MIP_FLAG_OTHER   = 0x40
+)
+
+// Home Agent Address Discovery Reply addresses after the ICMP6 header
+func (t *ICMP6) haadBody() ([]byte, error) {
+	hErr := errors.New("Error building Home Agent Address Discovery Reply")
+
+	b := make([]byte, 4, 4+16*len(t.MIP_HomeAgents))
+	/* 0 - 3 Reserved */
+	for _, a := range t.MIP_HomeAgents {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			fmt.Println("HAAD: could not parse home agent address", a)
+			return nil, hErr
+		}
+		b = append(b, ip.To16()...)
+	}
+	return b, nil
+}
+
+// decode the Home Agent Addresses of a Home Agent Address
+// Discovery Reply. b is the body after the ICMP6 header
+func (t *ICMP6) parseHAAD(b []byte) []byte {
+	if len(b) < 4 {
+		return b
+	}
+	for b = b[4:]; len(b) >= 16; b = b[16:] {
+		t.MIP_HomeAgents = append(t.MIP_HomeAgents, net.IP(b[0:16]).String())
+	}
+	return b
+}
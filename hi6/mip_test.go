@@ -0,0 +1,33 @@
+package hi6
+
+import "testing"
+
+func TestParseHomeAgentDiscoveryReply(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeHomeAgentAddressDiscoveryReply, ICMP6_id: 42,
+		MIP_HomeAgents: []string{"2001:db8::a", "2001:db8::b"}}
+	q := roundTrip(t, &p)
+
+	if q.ICMP6_id != 42 || len(q.MIP_HomeAgents) != 2 || q.MIP_HomeAgents[1] != "2001:db8::b" {
+		t.Fatalf("%+v", q)
+	}
+
+	p.Type, p.MIP_HomeAgents = ICMPTypeHomeAgentAddressDiscoveryRequest, nil
+	q = roundTrip(t, &p)
+	if q.Type != ICMPTypeHomeAgentAddressDiscoveryRequest || q.ICMP6_id != 42 {
+		t.Fatalf("%+v", q)
+	}
+}
+
+func TestParseMobilePrefixAdvertisement(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeMobilePrefixAdvertisement, ICMP6_id: 7, MIP_Flags: MIP_FLAG_MANAGED}
+	p.AddOption(Option{Type: OPT_PREFIX_INFORMATION, PI_Prefix_Len: 64, PI_Flags: OPT_FLAG_ROUTER,
+		PI_Valid_Time: 100, PI_Pref_Time: 50, Addr: "2001:db8::1"})
+	q := roundTrip(t, &p)
+
+	if q.ICMP6_id != 7 || q.MIP_Flags != MIP_FLAG_MANAGED || len(q.Options) != 1 ||
+		q.Options[0].PI_Flags != OPT_FLAG_ROUTER || q.Options[0].Addr != "2001:db8::1" {
+		t.Fatalf("%+v", q)
+	}
+}
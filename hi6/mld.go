@@ -0,0 +1,157 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// MLDv2 Multicast Address Record Types
+const (
+	MLD_MODE_IS_INCLUDE   = 1
+	MLD_MODE_IS_EXCLUDE   = 2
+	MLD_CHANGE_TO_INCLUDE = 3
+	MLD_CHANGE_TO_EXCLUDE = 4
+	MLD_ALLOW_NEW_SOURCES = 5
+	MLD_BLOCK_OLD_SOURCES = 6
+)
+
+// MLDv2 Query Flags
+const (
+	MLD_QUERY_FLAG_SUPPRESS = 0x08
+)
+
+// MLDv2 lengths
+const (
+	mldv2QueryLen        = 20
+	mldv2RecordHeaderLen = 20
+	mldv2QRVMask         = 0x07
+)
+
+// MLDRecord is a Multicast Address Record of a
+// Version 2 Multicast Listener Report
+type MLDRecord struct {
+	// Record Type (ie MLD_MODE_IS_INCLUDE, MLD_CHANGE_TO_EXCLUDE)
+	Type int
+
+	// Multicast Address
+	Addr string
+
+	// Source Addresses
+	Sources []string
+
+	// Auxiliary Data. Zero padded to 4 bytes
+	AuxData []byte
+}
+
+// Convenience function to add MLDv2 Multicast Address Records to ICMP6 struct
+func (t *ICMP6) AddMLDRecord(r MLDRecord) {
+	t.MLD_Records = append(t.MLD_Records, r)
+}
+
+// MLDv2 Query body after the ICMP6 header
+func (t *ICMP6) mldv2Query() ([]byte, error) {
+	qErr := errors.New("Error building MLDv2 Query")
+
+	b := make([]byte, mldv2QueryLen, mldv2QueryLen+16*len(t.MLD_Sources))
+	ip := net.ParseIP(t.MLD_Addr)
+	if ip == nil {
+		fmt.Println("MLDv2: could not parse MLD address")
+		return nil, qErr
+	}
+	copy(b[0:16], ip.To16())
+
+	b[16] = byte(t.MLD_QRV) & mldv2QRVMask
+	if t.MLD_SFlag {
+		b[16] |= MLD_QUERY_FLAG_SUPPRESS
+	}
+	b[17] = byte(t.MLD_QQIC)
+	binary.BigEndian.PutUint16(b[18:20], uint16(len(t.MLD_Sources)))
+
+	for _, s := range t.MLD_Sources {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			fmt.Println("MLDv2: could not parse source address", s)
+			return nil, qErr
+		}
+		b = append(b, ip.To16()...)
+	}
+	return b, nil
+}
+
+// Version 2 Multicast Listener Report body after the ICMP6 header
+func (t *ICMP6) mldv2Report() ([]byte, error) {
+	rErr := errors.New("Error building MLDv2 Report")
+
+	var b []byte
+	for _, r := range t.MLD_Records {
+		aux := make([]byte, (len(r.AuxData)+3)&^3)
+		copy(aux, r.AuxData)
+
+		rb := make([]byte, mldv2RecordHeaderLen)
+		rb[0] = byte(r.Type)
+		rb[1] = byte(len(aux) / 4)
+		binary.BigEndian.PutUint16(rb[2:4], uint16(len(r.Sources)))
+		ip := net.ParseIP(r.Addr)
+		if ip == nil {
+			fmt.Println("MLDv2: could not parse record address", r.Addr)
+			return nil, rErr
+		}
+		copy(rb[4:20], ip.To16())
+
+		for _, s := range r.Sources {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				fmt.Println("MLDv2: could not parse source address", s)
+				return nil, rErr
+			}
+			rb = append(rb, ip.To16()...)
+		}
+		rb = append(rb, aux...)
+		b = append(b, rb...)
+	}
+	return b, nil
+}
+
+// decode the MLDv2 Query fields after the Multicast Address
+func (t *ICMP6) parseMLDv2Query(b []byte) []byte {
+	t.MLD_V2 = true
+	t.MLD_SFlag = b[0]&MLD_QUERY_FLAG_SUPPRESS != 0
+	t.MLD_QRV = int(b[0] & mldv2QRVMask)
+	t.MLD_QQIC = int(b[1])
+	n := int(binary.BigEndian.Uint16(b[2:4]))
+	b = b[4:]
+	for i := 0; i < n && len(b) >= 16; i++ {
+		t.MLD_Sources = append(t.MLD_Sources, net.IP(b[0:16]).String())
+		b = b[16:]
+	}
+	return b
+}
+
+// decode the Multicast Address Records of a Version 2 Report.
+// n is the number of records from the ICMP6 header
+func (t *ICMP6) parseMLDv2Report(n int, b []byte) []byte {
+	for i := 0; i < n && len(b) >= mldv2RecordHeaderLen; i++ {
+		auxLen := int(b[1]) * 4
+		nsrc := int(binary.BigEndian.Uint16(b[2:4]))
+		l := mldv2RecordHeaderLen + 16*nsrc + auxLen
+		if l > len(b) {
+			break
+		}
+		r := MLDRecord{
+			Type: int(b[0]),
+			Addr: net.IP(b[4:20]).String(),
+		}
+		for j := 0; j < nsrc; j++ {
+			s := b[mldv2RecordHeaderLen+16*j : mldv2RecordHeaderLen+16*(j+1)]
+			r.Sources = append(r.Sources, net.IP(s).String())
+		}
+		if auxLen > 0 {
+			r.AuxData = append([]byte(nil), b[l-auxLen:l]...)
+		}
+		t.AddMLDRecord(r)
+		b = b[l:]
+	}
+	return b
+}
@@ -0,0 +1,39 @@
+package hi6
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMLDv2Query(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeMulticastListenerQuery, MLD_V2: true,
+		MLD_MaxDelay: 1000, MLD_Addr: "ff05::2", MLD_SFlag: true, MLD_QRV: 2, MLD_QQIC: 125,
+		MLD_Sources: []string{"2001:db8::1", "2001:db8::2"}}
+	q := roundTrip(t, &p)
+
+	if !q.MLD_V2 || q.MLD_MaxDelay != 1000 || q.MLD_Addr != "ff05::2" || !q.MLD_SFlag ||
+		q.MLD_QRV != 2 || q.MLD_QQIC != 125 {
+		t.Fatalf("%+v", q)
+	}
+	if len(q.MLD_Sources) != 2 || q.MLD_Sources[1] != "2001:db8::2" {
+		t.Fatalf("sources %v", q.MLD_Sources)
+	}
+}
+
+func TestParseMLDv2Report(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::16", SrcIP: "fe80::1", Type: ICMPTypeVersion2MulticastListenerReport}
+	p.AddMLDRecord(MLDRecord{Type: MLD_MODE_IS_INCLUDE, Addr: "ff05::3", Sources: []string{"2001:db8::5"}})
+	p.AddMLDRecord(MLDRecord{Type: MLD_CHANGE_TO_EXCLUDE, Addr: "ff05::4", AuxData: []byte{1, 2, 3, 4}})
+	q := roundTrip(t, &p)
+
+	if len(q.MLD_Records) != 2 {
+		t.Fatalf("got %d records", len(q.MLD_Records))
+	}
+	r0, r1 := q.MLD_Records[0], q.MLD_Records[1]
+	if r0.Type != MLD_MODE_IS_INCLUDE || r0.Addr != "ff05::3" || len(r0.Sources) != 1 || r0.Sources[0] != "2001:db8::5" {
+		t.Fatalf("record 0 %+v", r0)
+	}
+	if r1.Type != MLD_CHANGE_TO_EXCLUDE || r1.Addr != "ff05::4" || !bytes.Equal(r1.AuxData, []byte{1, 2, 3, 4}) {
+		t.Fatalf("record 1 %+v", r1)
+	}
+}
@@ -0,0 +1,138 @@
+package hi6
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MPL link-local all forwarders address
+const (
+	MPL_ALL_FORWARDERS = "ff02::fc"
+)
+
+// MPL Hop-by-Hop option type
+const (
+	EXTOPT_MPL = 0x6d
+)
+
+// MPL Option flags and lengths
+const (
+	mplOptMore      = 0x20
+	mplOptVersion   = 0x10
+	mplSeedInfoLen  = 2
+	mplBMLenMask    = 0x3f
+	mplSeedIDShift  = 6
+	mplOptHeaderLen = 2
+)
+
+// MPLSeed is an MPL Seed Info entry of an MPL Control message
+type MPLSeed struct {
+	// Lowest sequence number in the buffered messages bitmap
+	MinSeq int
+
+	// Seed ID of 0, 2, 8 or 16 bytes. Empty means the
+	// IPv6 Source Address is the Seed ID
+	SeedID []byte
+
+	// Buffered MPL messages bitmap, at most 63 bytes
+	Bitmap []byte
+}
+
+// Convenience function to add MPL Seed Info entries to ICMP6 struct
+func (t *ICMP6) AddMPLSeed(s MPLSeed) {
+	t.MPL_Seeds = append(t.MPL_Seeds, s)
+}
+
+// the S field for a Seed ID length
+func mplSeedIDType(n int) (byte, bool) {
+	switch n {
+	case 0:
+		return 0, true
+	case 2:
+		return 1, true
+	case 8:
+		return 2, true
+	case 16:
+		return 3, true
+	}
+	return 0, false
+}
+
+// the Seed ID length for an S field
+func mplSeedIDLen(s byte) int {
+	return [...]int{0, 2, 8, 16}[s&0x03]
+}
+
+// MPLOption returns an MPL option for a Hop-by-Hop header. more
+// sets the M flag. seedID must be 0, 2, 8 or 16 bytes
+func MPLOption(seq int, seedID []byte, more bool) (ExtOption, error) {
+	s, ok := mplSeedIDType(len(seedID))
+	if !ok {
+		fmt.Println("MPL: Seed ID must be 0, 2, 8 or 16 bytes")
+		return ExtOption{}, errors.New("Error building MPL option")
+	}
+	data := []byte{s << mplSeedIDShift, byte(seq)}
+	if more {
+		data[0] |= mplOptMore
+	}
+	data = append(data, seedID...)
+	return ExtOption{Type: EXTOPT_MPL, Data: data}, nil
+}
+
+// ParseMPLOption decodes the sequence, Seed ID and M flag of
+// an MPL option from a decoded Hop-by-Hop header
+func ParseMPLOption(o ExtOption) (seq int, seedID []byte, more bool, err error) {
+	if o.Type != EXTOPT_MPL || len(o.Data) < mplOptHeaderLen {
+		return 0, nil, false, errors.New("not an MPL option")
+	}
+	n := mplSeedIDLen(o.Data[0] >> mplSeedIDShift)
+	if len(o.Data) < mplOptHeaderLen+n {
+		return 0, nil, false, errors.New("truncated MPL option")
+	}
+	seq = int(o.Data[1])
+	more = o.Data[0]&mplOptMore != 0
+	if n > 0 {
+		seedID = append([]byte(nil), o.Data[mplOptHeaderLen:mplOptHeaderLen+n]...)
+	}
+	return seq, seedID, more, nil
+}
+
+// MPL Control message Seed Info entries. The first 4 bytes go
+// in the ICMP6 header data
+func (t *ICMP6) mplBody() ([]byte, error) {
+	mErr := errors.New("Error building MPL Control message")
+
+	var b []byte
+	for _, sd := range t.MPL_Seeds {
+		s, ok := mplSeedIDType(len(sd.SeedID))
+		if !ok || len(sd.Bitmap) > mplBMLenMask {
+			fmt.Println("MPL: Seed ID must be 0, 2, 8 or 16 bytes and Bitmap at most 63")
+			return nil, mErr
+		}
+		b = append(b, byte(sd.MinSeq), byte(len(sd.Bitmap))<<2|s)
+		b = append(b, sd.SeedID...)
+		b = append(b, sd.Bitmap...)
+	}
+	return b, nil
+}
+
+// decode the MPL Seed Info entries. b is the ICMP6 header
+// data followed by the body
+func (t *ICMP6) parseMPL(b []byte) []byte {
+	for len(b) >= mplSeedInfoLen {
+		bmLen := int(b[1]>>2) & mplBMLenMask
+		idLen := mplSeedIDLen(b[1])
+		l := mplSeedInfoLen + idLen + bmLen
+		if l > len(b) {
+			break
+		}
+		sd := MPLSeed{MinSeq: int(b[0])}
+		if idLen > 0 {
+			sd.SeedID = append([]byte(nil), b[mplSeedInfoLen:mplSeedInfoLen+idLen]...)
+		}
+		sd.Bitmap = append([]byte(nil), b[mplSeedInfoLen+idLen:l]...)
+		t.AddMPLSeed(sd)
+		b = b[l:]
+	}
+	return b
+}
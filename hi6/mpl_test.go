@@ -0,0 +1,64 @@
+package hi6
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseMPLControl(t *testing.T) {
+	p := ICMP6{DstIP: MPL_ALL_FORWARDERS, SrcIP: "fe80::1", Type: ICMPTypeMPLControl}
+	p.AddMPLSeed(MPLSeed{MinSeq: 10, SeedID: []byte{0xab, 0xcd}, Bitmap: []byte{0xff, 0x01}})
+	p.AddMPLSeed(MPLSeed{MinSeq: 200, SeedID: net.ParseIP("2001:db8::1"), Bitmap: []byte{0x80}})
+	q := roundTrip(t, &p)
+
+	if !reflect.DeepEqual(q.MPL_Seeds, p.MPL_Seeds) {
+		t.Fatalf("%+v", q.MPL_Seeds)
+	}
+	if len(q.Data) != 0 {
+		t.Fatalf("Data % x", q.Data)
+	}
+}
+
+func TestParseMPLControlShort(t *testing.T) {
+	tests := []struct {
+		seeds []MPLSeed
+		size  int
+	}{
+		{nil, 4},
+		{[]MPLSeed{{MinSeq: 1}}, 6},
+		{[]MPLSeed{{MinSeq: 1, Bitmap: []byte{0x0f}}}, 7},
+		{[]MPLSeed{{MinSeq: 1}, {MinSeq: 2, Bitmap: []byte{0x0f}}}, 9},
+	}
+	for _, tt := range tests {
+		p := ICMP6{DstIP: MPL_ALL_FORWARDERS, SrcIP: "fe80::1", Type: ICMPTypeMPLControl, MPL_Seeds: tt.seeds}
+		q := roundTrip(t, &p)
+
+		// no padding and no phantom seeds
+		if n := len(q.frame) - IPHeaderLen; n != tt.size {
+			t.Errorf("%d seeds: message is %d bytes, want %d", len(tt.seeds), n, tt.size)
+		}
+		if len(q.MPL_Seeds) != len(tt.seeds) {
+			t.Errorf("sent %d seeds, got %+v", len(tt.seeds), q.MPL_Seeds)
+		}
+	}
+}
+
+func TestMPLOption(t *testing.T) {
+	o, err := MPLOption(42, []byte{1, 2, 3, 4, 5, 6, 7, 8}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ICMP6{DstIP: MPL_ALL_FORWARDERS, SrcIP: "fe80::1", Type: ICMPTypeEchoRequest}
+	p.AddExtHeader(ExtHeader{Type: EXT_HOPBYHOP, Options: []ExtOption{o}})
+	q := roundTrip(t, &p)
+
+	seq, id, more, err := ParseMPLOption(q.ExtHeaders[0].Options[0])
+	if err != nil || seq != 42 || !more || !bytes.Equal(id, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("%d % x %v %v", seq, id, more, err)
+	}
+	if _, err := MPLOption(1, []byte{1, 2, 3}, false); err == nil {
+		t.Fatal("expected error for a 3 byte Seed ID")
+	}
+}
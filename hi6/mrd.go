@@ -0,0 +1,33 @@
+package hi6
+
+import (
+	"encoding/binary"
+)
+
+// Multicast Router Discovery destinations
+const (
+	MRD_ALL_SNOOPERS = "ff02::6a"
+	MRD_ALL_ROUTERS  = "ff02::2"
+)
+
+// the destination Multicast Router Discovery messages go to. Routers
+// advertise and terminate to the snoopers and are solicited by them
+func mrdDstIP(typ ICMPType) string {
+	switch typ {
+	case ICMPTypeMulticastRouterAdvertisement, ICMPTypeMulticastRouterTermination:
+		return MRD_ALL_SNOOPERS
+	case ICMPTypeMulticastRouterSolicitation:
+		return MRD_ALL_ROUTERS
+	}
+	return ""
+}
+
+// decode the Multicast Router Advertisement fields from the ICMP6
+// header data. The Advertisement Interval is the Code
+func (t *ICMP6) parseMRD(data []byte) {
+	if t.Type != ICMPTypeMulticastRouterAdvertisement {
+		return
+	}
+	t.MRD_QueryInterval = binary.BigEndian.Uint16(data[0:2])
+	t.MRD_Robustness = binary.BigEndian.Uint16(data[2:4])
+}
@@ -0,0 +1,40 @@
+package hi6
+
+import "testing"
+
+// length of the Router Alert Hop-by-Hop header
+const hbhLen = 8
+
+func TestParseMRDAdvertisement(t *testing.T) {
+	p := ICMP6{SrcIP: "fe80::1", Type: ICMPTypeMulticastRouterAdvertisement, Code: 20,
+		MRD_QueryInterval: 125, MRD_Robustness: 2}
+	q := roundTrip(t, &p)
+
+	if q.DstIP != MRD_ALL_SNOOPERS || q.HopLimit != 1 || len(q.ExtHeaders) != 1 {
+		t.Fatalf("%+v", q)
+	}
+	if q.Code != 20 || q.MRD_QueryInterval != 125 || q.MRD_Robustness != 2 {
+		t.Fatalf("%+v", q)
+	}
+	if n := len(q.frame) - IPHeaderLen - hbhLen; n != ICMPHeaderLen {
+		t.Fatalf("Advertisement is %d bytes", n)
+	}
+}
+
+func TestParseMRDSolicitation(t *testing.T) {
+	for _, typ := range []ICMPType{ICMPTypeMulticastRouterSolicitation, ICMPTypeMulticastRouterTermination} {
+		p := ICMP6{SrcIP: "fe80::1", Type: typ}
+		q := roundTrip(t, &p)
+
+		if q.Type != typ || q.DstIP != mrdDstIP(typ) || q.HopLimit != 1 {
+			t.Fatalf("%+v", q)
+		}
+		// RFC 4286 messages without the reserved header data
+		if n := len(q.frame) - IPHeaderLen - hbhLen; n != icmpMinLen {
+			t.Fatalf("type %d is %d bytes", typ, n)
+		}
+		if len(q.Data) != 0 {
+			t.Fatalf("Data % x", q.Data)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package hi6
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Node Information Qtypes
+const (
+	NI_QTYPE_NOOP       = 0
+	NI_QTYPE_NODE_NAME  = 2
+	NI_QTYPE_NODE_ADDRS = 3
+	NI_QTYPE_IPV4_ADDRS = 4
+)
+
+// Node Information Query Codes give the Subject type
+const (
+	NI_SUBJECT_IPV6 = 0
+	NI_SUBJECT_NAME = 1
+	NI_SUBJECT_IPV4 = 2
+)
+
+// Node Information Reply Codes
+const (
+	NI_REPLY_SUCCESS       = 0
+	NI_REPLY_REFUSED       = 1
+	NI_REPLY_UNKNOWN_QTYPE = 2
+)
+
+// Node Information Flags for Node Addresses and IPv4 Addresses
+const (
+	NI_FLAG_TRUNCATE  = 0x0001
+	NI_FLAG_ALL       = 0x0002
+	NI_FLAG_COMPAT    = 0x0004
+	NI_FLAG_LINKLOCAL = 0x0008
+	NI_FLAG_SITELOCAL = 0x0010
+	NI_FLAG_GLOBAL    = 0x0020
+)
+
+// Node Information lengths
+const (
+	niNonceLen         = 8
+	niTTLLen           = 4
+	niIPv4AddrEntryLen = niTTLLen + net.IPv4len
+	niIPv6AddrEntryLen = niTTLLen + net.IPv6len
+)
+
+// NIAddr is an address with its TTL in a Node Information Reply
+type NIAddr struct {
+	TTL  uint32
+	Addr string
+}
+
+// Node Information body after the ICMP6 header. The Nonce is
+// generated if not set
+func (t *ICMP6) niBody() ([]byte, error) {
+	nErr := errors.New("Error building Node Information message")
+
+	if t.NI_Nonce == [niNonceLen]byte{} {
+		if _, err := rand.Read(t.NI_Nonce[:]); err != nil {
+			fmt.Println("NI: could not generate nonce")
+			return nil, nErr
+		}
+	}
+	b := append([]byte(nil), t.NI_Nonce[:]...)
+
+	if t.Type == ICMPTypeNodeInformationQuery {
+		if t.NI_Subject == "" {
+			return b, nil
+		}
+		switch t.Code {
+		case NI_SUBJECT_IPV6:
+			ip := net.ParseIP(t.NI_Subject)
+			if ip == nil {
+				fmt.Println("NI: could not parse IPv6 subject")
+				return nil, nErr
+			}
+			b = append(b, ip.To16()...)
+		case NI_SUBJECT_IPV4:
+			ip := net.ParseIP(t.NI_Subject).To4()
+			if ip == nil {
+				fmt.Println("NI: could not parse IPv4 subject")
+				return nil, nErr
+			}
+			b = append(b, ip...)
+		case NI_SUBJECT_NAME:
+			name, err := dnsEncodeName(t.NI_Subject)
+			if err != nil {
+				return nil, nErr
+			}
+			b = append(b, name...)
+		}
+		return b, nil
+	}
+
+	// replies only carry data on success
+	if t.Code != NI_REPLY_SUCCESS {
+		return b, nil
+	}
+	switch t.NI_Qtype {
+	case NI_QTYPE_NODE_NAME:
+		ttl := make([]byte, niTTLLen)
+		binary.BigEndian.PutUint32(ttl, t.NI_TTL)
+		b = append(b, ttl...)
+		for _, n := range t.NI_Names {
+			name, err := dnsEncodeName(n)
+			if err != nil {
+				return nil, nErr
+			}
+			b = append(b, name...)
+		}
+	case NI_QTYPE_NODE_ADDRS, NI_QTYPE_IPV4_ADDRS:
+		for _, a := range t.NI_Addrs {
+			ip := net.ParseIP(a.Addr).To16()
+			if t.NI_Qtype == NI_QTYPE_IPV4_ADDRS {
+				ip = ip.To4()
+			}
+			if ip == nil {
+				fmt.Println("NI: could not parse address", a.Addr)
+				return nil, nErr
+			}
+			ttl := make([]byte, niTTLLen)
+			binary.BigEndian.PutUint32(ttl, a.TTL)
+			b = append(b, ttl...)
+			b = append(b, ip...)
+		}
+	}
+	return b, nil
+}
+
+// decode the Node Information fields. data is the ICMP6 header
+// data and body everything after it
+func (t *ICMP6) parseNI(data []byte, body []byte) []byte {
+	t.NI_Qtype = int(binary.BigEndian.Uint16(data[0:2]))
+	t.NI_Flags = int(binary.BigEndian.Uint16(data[2:4]))
+	if len(body) < niNonceLen {
+		return body
+	}
+	copy(t.NI_Nonce[:], body[:niNonceLen])
+	b := body[niNonceLen:]
+
+	if t.Type == ICMPTypeNodeInformationQuery {
+		switch {
+		case len(b) == 0:
+		case t.Code == NI_SUBJECT_IPV6 && len(b) >= net.IPv6len:
+			t.NI_Subject = net.IP(b[:net.IPv6len]).String()
+		case t.Code == NI_SUBJECT_IPV4 && len(b) >= net.IPv4len:
+			t.NI_Subject = net.IP(b[:net.IPv4len]).String()
+		case t.Code == NI_SUBJECT_NAME:
+			if names := dnsDecodeNames(b); len(names) > 0 {
+				t.NI_Subject = names[0]
+			}
+		default:
+			return b
+		}
+		return nil
+	}
+
+	if t.Code != NI_REPLY_SUCCESS {
+		return b
+	}
+	switch t.NI_Qtype {
+	case NI_QTYPE_NODE_NAME:
+		if len(b) < niTTLLen {
+			return b
+		}
+		t.NI_TTL = binary.BigEndian.Uint32(b[0:4])
+		t.NI_Names = dnsDecodeNames(b[niTTLLen:])
+		return nil
+	case NI_QTYPE_NODE_ADDRS:
+		for ; len(b) >= niIPv6AddrEntryLen; b = b[niIPv6AddrEntryLen:] {
+			t.NI_Addrs = append(t.NI_Addrs, NIAddr{
+				TTL:  binary.BigEndian.Uint32(b[0:4]),
+				Addr: net.IP(b[4:20]).String(),
+			})
+		}
+	case NI_QTYPE_IPV4_ADDRS:
+		for ; len(b) >= niIPv4AddrEntryLen; b = b[niIPv4AddrEntryLen:] {
+			t.NI_Addrs = append(t.NI_Addrs, NIAddr{
+				TTL:  binary.BigEndian.Uint32(b[0:4]),
+				Addr: net.IP(b[4:8]).String(),
+			})
+		}
+	}
+	return b
+}
@@ -0,0 +1,34 @@
+package hi6
+
+import "testing"
+
+func TestParseNIQuery(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeNodeInformationQuery, Code: NI_SUBJECT_NAME, NI_Qtype: NI_QTYPE_NODE_ADDRS,
+		NI_Flags: NI_FLAG_GLOBAL | NI_FLAG_ALL, NI_Subject: "host.example"}
+	q := roundTrip(t, &p)
+
+	if p.NI_Nonce == [8]byte{} || q.NI_Nonce != p.NI_Nonce {
+		t.Fatalf("nonce % x % x", p.NI_Nonce, q.NI_Nonce)
+	}
+	if q.NI_Qtype != NI_QTYPE_NODE_ADDRS || q.NI_Flags != NI_FLAG_GLOBAL|NI_FLAG_ALL || q.NI_Subject != "host.example" {
+		t.Fatalf("%+v", q)
+	}
+}
+
+func TestParseNIReply(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeNodeInformationResponse, Code: NI_REPLY_SUCCESS, NI_Qtype: NI_QTYPE_NODE_NAME,
+		NI_TTL: 300, NI_Names: []string{"host.example", "alias"}}
+	q := roundTrip(t, &p)
+	if q.NI_TTL != 300 || len(q.NI_Names) != 2 || q.NI_Names[0] != "host.example" || q.NI_Names[1] != "alias" {
+		t.Fatalf("%+v", q)
+	}
+
+	p.NI_Qtype = NI_QTYPE_IPV4_ADDRS
+	p.NI_Addrs = []NIAddr{{TTL: 60, Addr: "192.0.2.1"}, {TTL: 0, Addr: "192.0.2.2"}}
+	q = roundTrip(t, &p)
+	if len(q.NI_Addrs) != 2 || q.NI_Addrs[0] != p.NI_Addrs[0] || q.NI_Addrs[1] != p.NI_Addrs[1] {
+		t.Fatalf("%+v", q.NI_Addrs)
+	}
+}
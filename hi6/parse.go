@@ -0,0 +1,419 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Parse decodes an Ethernet frame or a bare IPv6 packet
+// into a new ICMP6. See UnmarshalBinary
+func Parse(b []byte) (*ICMP6, error) {
+	t := new(ICMP6)
+	if err := t.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// UnmarshalBinary fills in t from an Ethernet frame or a bare IPv6
+// packet. Addresses, Type, Code, the type specific fields and Options
+// are decoded. Whatever is left of the ICMP6 payload goes to Data.
+// Checksum holds the received checksum and ChecksumValid reports
+// whether it was correct. The decoded packet can be sent again with Send
+func (t *ICMP6) UnmarshalBinary(b []byte) error {
+	if err := t.unmarshal(b); err != nil {
+		fmt.Println(err)
+		return errors.New("Cannot Parse ICMP6 Packet")
+	}
+	return nil
+}
+
+// does the work for UnmarshalBinary without printing, so the
+// Listener can quietly drop frames that are not ICMP6
+func (t *ICMP6) unmarshal(b []byte) error {
+	*t = ICMP6{}
+
+	pkt := b
+	if !isIP6Packet(b) {
+		if len(b) < EtherLen {
+			return errors.New("Parse: frame too short")
+		}
+		etype := binary.BigEndian.Uint16(b[12:14])
+		hdrLen := EtherLen
+		// skip 802.1Q tag
+		if etype == 0x8100 && len(b) >= EtherLen+4 {
+			etype = binary.BigEndian.Uint16(b[16:18])
+			hdrLen += 4
+		}
+		if etype != 0x86dd {
+			return errors.New("Parse: not an IPv6 frame")
+		}
+		t.DstMAC = net.HardwareAddr(b[0:6]).String()
+		t.SrcMAC = net.HardwareAddr(b[6:12]).String()
+		pkt = b[hdrLen:]
+	}
+
+	if len(pkt) < IPHeaderLen || pkt[0]>>4 != 6 {
+		return errors.New("Parse: not an IPv6 packet")
+	}
+	// strip ethernet padding
+	plen := int(binary.BigEndian.Uint16(pkt[4:6]))
+	if IPHeaderLen+plen > len(pkt) {
+		return errors.New("Parse: truncated IPv6 packet")
+	}
+	pkt = pkt[:IPHeaderLen+plen]
+
+	src := net.IP(pkt[8:24])
+	dst := net.IP(pkt[24:40])
+	t.SrcIP = src.String()
+	t.DstIP = dst.String()
+	t.HopLimit = int(pkt[7])
+	t.HopLimitOverride = true
+
+	nh, off, exts, err := walkExtHeaders(pkt)
+	if err != nil {
+		return errors.New("Parse: " + err.Error())
+	}
+	if nh != syscall.IPPROTO_ICMPV6 {
+		return errors.New("Parse: not an ICMP6 packet")
+	}
+	// MRD, MPL and RPL messages may be shorter than ICMPHeaderLen
+	msg := pkt[off:]
+	if len(msg) < icmpMinLen {
+		return errors.New("Parse: ICMP6 header too short")
+	}
+
+	// keep a copy so the packet can be sent again
+	t.frame = make([]byte, len(pkt))
+	copy(t.frame, pkt)
+
+	t.ExtHeaders = exts
+
+	t.Type = ICMPType(msg[0])
+	t.Code = int(msg[1])
+	t.Checksum = binary.BigEndian.Uint16(msg[2:4])
+	copy(t.ICMPData[:], msg[4:])
+
+	zmsg := make([]byte, len(msg))
+	copy(zmsg, msg)
+	zmsg[2] = 0
+	zmsg[3] = 0
+	cs := pseudoCsum(src, finalDst(exts, dst), syscall.IPPROTO_ICMPV6, zmsg)
+	t.ChecksumValid = msg[2] == byte(cs) && msg[3] == byte(cs>>8)
+
+	if len(msg) < ICMPHeaderLen {
+		t.parseBody(msg[4:], nil)
+		return nil
+	}
+	t.parseBody(msg[4:8], msg[ICMPHeaderLen:])
+	return nil
+}
+
+// decode the type specific fields. data is the ICMP6 header data
+// and body everything after the ICMP6 header. data is short for
+// messages shorter than ICMPHeaderLen
+func (t *ICMP6) parseBody(data []byte, body []byte) {
+	rest := body
+	hasOptions := false
+
+	hdata := data
+	if len(data) < 4 {
+		data = make([]byte, 4)
+		copy(data, hdata)
+	}
+
+	if isErrorMessage(t.Type) {
+		t.Invoking = parseInvoking(body)
+		if t.Invoking != nil {
+			rest = nil
+		}
+	}
+
+	switch t.Type {
+	case ICMPTypeParameterProblem:
+		t.ICMP6_pptr = binary.BigEndian.Uint32(data)
+	case ICMPTypePacketTooBig:
+		t.ICMP6_mtu = binary.BigEndian.Uint32(data)
+	case ICMPTypeEchoRequest, ICMPTypeEchoReply:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+		t.ICMP6_seq = binary.BigEndian.Uint16(data[2:4])
+	case ICMPTypeRouterSolicitation, ICMPTypeInverseNeighborDiscoverySolicitation,
+		ICMPTypeInverseNeighborDiscoveryAdvertisement:
+		hasOptions = true
+	case ICMPTypeRouterAdvertisement:
+		t.RA_Curhoplimit = int(data[0])
+		t.RA_Flags = int(data[1])
+		t.RA_Router_lifetime = binary.BigEndian.Uint16(data[2:4])
+		if len(body) >= 8 {
+			t.RA_Reachable = binary.BigEndian.Uint32(body[0:4])
+			t.RA_Retransmit = binary.BigEndian.Uint32(body[4:8])
+			rest = body[8:]
+			hasOptions = true
+		}
+	case ICMPTypeNeighborSolicitation, ICMPTypeNeighborAdvertisement:
+		if t.Type == ICMPTypeNeighborAdvertisement {
+			t.NA_Flags = int(data[0])
+		}
+		if len(body) >= 16 {
+			t.TargetAddr = net.IP(body[0:16]).String()
+			rest = body[16:]
+			hasOptions = true
+		}
+	case ICMPTypeRedirect:
+		if len(body) >= 32 {
+			t.TargetAddr = net.IP(body[0:16]).String()
+			t.DestAddr = net.IP(body[16:32]).String()
+			rest = body[32:]
+			hasOptions = true
+		}
+	case ICMPTypeMulticastListenerQuery, ICMPTypeMulticastListenerReport,
+		ICMPTypeMulticastListenerDone:
+		t.MLD_MaxDelay = binary.BigEndian.Uint16(data[0:2])
+		if len(body) >= 16 {
+			t.MLD_Addr = net.IP(body[0:16]).String()
+			rest = body[16:]
+		}
+		// MLDv2 Queries are at least 28 bytes
+		if t.Type == ICMPTypeMulticastListenerQuery && len(body) >= mldv2QueryLen {
+			rest = t.parseMLDv2Query(body[16:])
+		}
+	case ICMPTypeVersion2MulticastListenerReport:
+		rest = t.parseMLDv2Report(int(binary.BigEndian.Uint16(data[2:4])), body)
+	case ICMPTypeHomeAgentAddressDiscoveryRequest, ICMPTypeMobilePrefixSolicitation:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+	case ICMPTypeHomeAgentAddressDiscoveryReply:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+		rest = t.parseHAAD(body)
+	case ICMPTypeMobilePrefixAdvertisement:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+		t.MIP_Flags = int(data[2])
+		hasOptions = true
+	case ICMPTypeCertificationPathSolicitation:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+		t.CP_Component = int(binary.BigEndian.Uint16(data[2:4]))
+		hasOptions = true
+	case ICMPTypeCertificationPathAdvertisement:
+		t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+		t.CP_AllComponents = int(binary.BigEndian.Uint16(data[2:4]))
+		if len(body) >= 4 {
+			t.CP_Component = int(binary.BigEndian.Uint16(body[0:2]))
+			rest = body[4:]
+			hasOptions = true
+		}
+	case ICMPTypeFMIPv6:
+		t.parseFMIP(data)
+		hasOptions = true
+	case ICMPTypeILNPv6LocatorUpdate:
+		rest = t.parseILNP(data, body)
+	case ICMPTypeMulticastRouterAdvertisement, ICMPTypeMulticastRouterSolicitation,
+		ICMPTypeMulticastRouterTermination:
+		t.parseMRD(data)
+	case ICMPTypeExtendedEchoRequest, ICMPTypeExtendedEchoReply:
+		rest = t.parseXEcho(data, body)
+	case ICMPTypeNodeInformationQuery, ICMPTypeNodeInformationResponse:
+		rest = t.parseNI(data, body)
+	case ICMPTypeMPLControl:
+		rest = t.parseMPL(append(append([]byte(nil), hdata...), body...))
+	case ICMPTypeRPLControl:
+		rest = t.parseRPL(append(append([]byte(nil), hdata...), body...))
+	case ICMPTypeDuplicateAddressRequest, ICMPTypeDuplicateAddressConfirmation:
+		rest = t.parseDAR(data, body)
+	case ICMPTypeRouterRenumbering:
+		t.RR_Seqnum = int(binary.BigEndian.Uint32(data))
+		if len(body) >= 32 {
+			t.RR_Segnum = int(body[0])
+			t.RR_Flags = int(body[1])
+			t.RR_MaxDelay = int(binary.BigEndian.Uint16(body[2:4]))
+
+			t.RR_PCOMatch.Code = int(body[8])
+			t.RR_PCOMatch.Len = int(body[9])
+			t.RR_PCOMatch.Ordinal = int(body[10])
+			t.RR_PCOMatch.MatchLen = int(body[11])
+			t.RR_PCOMatch.MinLen = int(body[12])
+			t.RR_PCOMatch.MaxLen = int(body[13])
+			t.RR_PCOMatch.Prefix = net.IP(body[16:32]).String()
+			rest = body[32:]
+
+			// PCO Use parts follow the match part
+			for i := 0; i < (t.RR_PCOMatch.Len-3)/4 && len(rest) >= 32; i++ {
+				use := PCOUse{
+					UseLen:           int(rest[0]),
+					KeepLen:          int(rest[1]),
+					RA_Mask:          int(rest[2]),
+					RA_Flags:         int(rest[3]),
+					ValidLifetime:    int(binary.BigEndian.Uint32(rest[4:8])),
+					PreferedLifetime: int(binary.BigEndian.Uint32(rest[8:12])),
+					Flags:            int(binary.BigEndian.Uint32(rest[12:16])),
+					Prefix:           net.IP(rest[16:32]).String(),
+				}
+				t.AddPCOUse(use)
+				rest = rest[32:]
+			}
+		}
+	}
+
+	if hasOptions {
+		rest = t.parseOptions(rest)
+	}
+	if len(rest) > 0 {
+		t.Data = make([]byte, len(rest))
+		copy(t.Data, rest)
+	}
+	t.DataLen = len(t.Data)
+}
+
+// decode ICMP6 options into t.Options. Stops at the first malformed
+// option and returns the bytes that could not be decoded
+func (t *ICMP6) parseOptions(b []byte) []byte {
+	for len(b) >= 2 {
+		l := int(b[1]) * 8
+		if l == 0 || l > len(b) {
+			return b
+		}
+		ob := b[:l]
+		o := Option{Type: int(ob[0])}
+
+		switch o.Type {
+		case OPT_SOURCE_LINKADDR, OPT_TARGET_LINKADDR:
+			o.Addr = net.HardwareAddr(ob[2:8]).String()
+		case OPT_PREFIX_INFORMATION:
+			if l != 32 {
+				o.Raw = append([]byte(nil), ob...)
+				break
+			}
+			o.PI_Prefix_Len = int(ob[2])
+			o.PI_Flags = ob[3]
+			o.PI_Valid_Time = binary.BigEndian.Uint32(ob[4:8])
+			o.PI_Pref_Time = binary.BigEndian.Uint32(ob[8:12])
+			o.Addr = net.IP(ob[16:32]).String()
+		case OPT_MTU:
+			o.MTU = binary.BigEndian.Uint32(ob[4:8])
+		case OPT_SOURCE_ADDR_LIST, OPT_TARGET_ADDR_LIST:
+			for ab := ob[8:]; len(ab) >= 16; ab = ab[16:] {
+				o.Addrs = append(o.Addrs, net.IP(ab[0:16]).String())
+			}
+		case OPT_DNSSL:
+			o.DNSSL_Lifetime = binary.BigEndian.Uint32(ob[4:8])
+			o.DNSSL_Domains = dnsDecodeNames(ob[8:])
+		case OPT_ROUTE_INFORMATION:
+			o.PI_Prefix_Len = int(ob[2])
+			o.RIO_Pref = ob[3] & RA_FLAG_PREF_LOW
+			o.RIO_Lifetime = binary.BigEndian.Uint32(ob[4:8])
+			prefix := make(net.IP, net.IPv6len)
+			copy(prefix, ob[8:])
+			o.Addr = prefix.String()
+		case OPT_RDNS:
+			o.RDNS_Lifetime = binary.BigEndian.Uint32(ob[4:8])
+			for ab := ob[8:]; len(ab) >= 16; ab = ab[16:] {
+				o.RDNS_Servers = append(o.RDNS_Servers, net.IP(ab[0:16]).String())
+			}
+		case OPT_IP_ADDR_PREFIX, OPT_LINKADDR:
+			o.parseFMIP(ob)
+		case OPT_CGA:
+			if int(ob[2]) <= l-sendOptHeaderLen {
+				o.CGA_Params = append([]byte(nil), ob[sendOptHeaderLen:l-int(ob[2])]...)
+			}
+		case OPT_RSA_SIGNATURE:
+			if l < sigHeaderLen {
+				o.Raw = append([]byte(nil), ob...)
+				break
+			}
+			// the padding is kept with the signature
+			o.SIG_KeyHash = append([]byte(nil), ob[4:sigHeaderLen]...)
+			o.SIG_Data = append([]byte(nil), ob[sigHeaderLen:]...)
+		case OPT_TIMESTAMP:
+			if l < tsLen {
+				o.Raw = append([]byte(nil), ob...)
+				break
+			}
+			o.parseTimestamp(ob)
+		case OPT_NONCE:
+			o.Nonce = append([]byte(nil), ob[2:]...)
+		case OPT_TRUST_ANCHOR:
+			o.parseTrustAnchor(ob)
+		case OPT_CERTIFICATE:
+			o.parseCertificate(ob)
+		case OPT_ADDR_REGISTRATION:
+			if l < aroLen {
+				o.Raw = append([]byte(nil), ob...)
+				break
+			}
+			o.ARO_Status = int(ob[2])
+			o.ARO_Opaque = int(ob[3])
+			o.ARO_Flags = int(ob[4])
+			o.ARO_TID = int(ob[5])
+			o.ARO_Lifetime = binary.BigEndian.Uint16(ob[6:8])
+			o.ARO_ROVR = append([]byte(nil), ob[8:]...)
+		case OPT_6LOWPAN_CONTEXT:
+			if l < coShortLen {
+				o.Raw = append([]byte(nil), ob...)
+				break
+			}
+			o.CO_Len = int(ob[2])
+			o.CO_Compress = ob[3]&CO_FLAG_COMPRESS != 0
+			o.CO_CID = int(ob[3] & CO_CID_MASK)
+			o.CO_Lifetime = binary.BigEndian.Uint16(ob[6:8])
+			prefix := make(net.IP, net.IPv6len)
+			copy(prefix, ob[8:])
+			o.Addr = prefix.String()
+		default:
+			o.Raw = append([]byte(nil), ob...)
+		}
+		t.AddOption(o)
+		b = b[l:]
+	}
+	return b
+}
+
+// looks like a bare IPv6 packet rather than an Ethernet frame
+func isIP6Packet(b []byte) bool {
+	if len(b) < IPHeaderLen || b[0]>>4 != 6 {
+		return false
+	}
+	return IPHeaderLen+int(binary.BigEndian.Uint16(b[4:6])) == len(b)
+}
+
+// walk the extension header chain of an IPv6 packet. Returns the
+// upper layer protocol, the offset of its header and the decoded
+// extension headers
+func walkExtHeaders(pkt []byte) (int, int, []ExtHeader, error) {
+	var exts []ExtHeader
+	nh := int(pkt[6])
+	off := IPHeaderLen
+	for {
+		l := 0
+		switch nh {
+		case EXT_HOPBYHOP, EXT_ROUTING, EXT_DSTOPTS:
+			if off+8 > len(pkt) {
+				return 0, 0, nil, errors.New("truncated extension header")
+			}
+			l = (int(pkt[off+1]) + 1) * 8
+			if off+l > len(pkt) {
+				return 0, 0, nil, errors.New("truncated extension header")
+			}
+		case EXT_FRAGMENT:
+			if off+8 > len(pkt) {
+				return 0, 0, nil, errors.New("truncated fragment header")
+			}
+			if binary.BigEndian.Uint16(pkt[off+2:off+4])&0xfff8 != 0 {
+				return 0, 0, nil, errors.New("not the first fragment")
+			}
+			l = 8
+		default:
+			return nh, off, exts, nil
+		}
+		exts = append(exts, parseExtHeader(nh, pkt[off:off+l]))
+		nh = int(pkt[off])
+		off += l
+	}
+}
+
+// used to compare addresses given as strings
+func sameIP(a, b string) bool {
+	ipa := net.ParseIP(a)
+	ipb := net.ParseIP(b)
+	return ipa != nil && ipb != nil && ipa.Equal(ipb)
+}
@@ -0,0 +1,99 @@
+package hi6
+
+import (
+	"testing"
+)
+
+// build p on the loopback interface and parse the frame back
+func roundTrip(t *testing.T, p *ICMP6) *ICMP6 {
+	t.Helper()
+	if p.Iface == "" {
+		p.Iface = "lo"
+	}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.ChecksumValid {
+		t.Fatal("bad checksum")
+	}
+	return q
+}
+
+func TestParseRouterAdvertisement(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", SrcMAC: "10:0b:a9:aa:aa:aa",
+		Type: ICMPTypeRouterAdvertisement, RA_Flags: RA_FLAG_OTHER, RA_Curhoplimit: 64,
+		RA_Router_lifetime: 9000, RA_Reachable: 5, RA_Retransmit: 6}
+	p.AddOption(Option{Type: OPT_SOURCE_LINKADDR, Addr: "10:0b:a9:bb:bb:bb"})
+	p.AddOption(Option{Type: OPT_PREFIX_INFORMATION, PI_Prefix_Len: 64, PI_Flags: OPT_FLAG_AUTO,
+		PI_Valid_Time: 1, PI_Pref_Time: 2, Addr: "2001:db8::"})
+	p.AddOption(Option{Type: OPT_MTU, MTU: 1400})
+
+	q := roundTrip(t, &p)
+	if q.Type != p.Type || q.RA_Flags != p.RA_Flags || q.RA_Curhoplimit != 64 ||
+		q.RA_Router_lifetime != 9000 || q.RA_Reachable != 5 || q.RA_Retransmit != 6 {
+		t.Fatalf("header %+v", q)
+	}
+	if q.SrcMAC != "10:0b:a9:aa:aa:aa" || q.DstMAC != "33:33:00:00:00:01" || q.SrcIP != "fe80::1" || q.DstIP != "ff02::1" {
+		t.Fatalf("addresses %+v", q)
+	}
+	if len(q.Options) != 3 || q.Options[0].Addr != "10:0b:a9:bb:bb:bb" ||
+		q.Options[1].Addr != "2001:db8::" || q.Options[1].PI_Pref_Time != 2 || q.Options[2].MTU != 1400 {
+		t.Fatalf("options %+v", q.Options)
+	}
+}
+
+func TestParseNeighborAdvertisement(t *testing.T) {
+	p := ICMP6{DstIP: "fe80::2", SrcIP: "fe80::1", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeNeighborAdvertisement, NA_Flags: NA_FLAG_OVERRIDE | NA_FLAG_SOLICITED, TargetAddr: "fe80::1"}
+	p.AddOption(Option{Type: OPT_TARGET_LINKADDR, Addr: "10:0b:a9:bb:bb:bb"})
+
+	q := roundTrip(t, &p)
+	if q.NA_Flags != p.NA_Flags || q.TargetAddr != "fe80::1" || len(q.Options) != 1 || q.Options[0].Type != OPT_TARGET_LINKADDR {
+		t.Fatalf("%+v", q)
+	}
+}
+
+func TestParseEchoBareIPv6(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoRequest, ICMP6_id: 7, ICMP6_seq: 9, Data: []byte("abcdefgh"), DataLen: 8}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.MarshalBinary()
+
+	// without the Ethernet header
+	q, err := Parse(b[EtherLen:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.ChecksumValid || q.ICMP6_id != 7 || q.ICMP6_seq != 9 || string(q.Data) != "abcdefgh" {
+		t.Fatalf("%+v", q)
+	}
+}
+
+func TestParseNotICMP6(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoRequest}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.MarshalBinary()
+
+	ip4 := append([]byte(nil), b...)
+	ip4[12], ip4[13] = 0x08, 0x00
+	udp := append([]byte(nil), b...)
+	udp[EtherLen+6] = 17
+	for _, f := range [][]byte{ip4, udp, b[:EtherLen+IPHeaderLen+4]} {
+		if _, err := Parse(f); err == nil {
+			t.Fatalf("parsed % x", f)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// pcap Link Type for Ethernet frames
+const LINKTYPE_ETHERNET = 1
+
+// pcap and pcapng magic numbers
+const (
+	pcapMagic       = 0xa1b2c3d4
+	pcapMagicNsec   = 0xa1b23c4d
+	pcapngSHB       = 0x0a0d0d0a
+	pcapngIDB       = 0x00000001
+	pcapngEPB       = 0x00000006
+	pcapngByteOrder = 0x1a2b3c4d
+	pcapSnapLen     = 65535
+)
+
+// FrameWriter records Ethernet frames with a timestamp.
+// Implemented by PcapWriter and PcapngWriter
+type FrameWriter interface {
+	WriteFrame(ts time.Time, frame []byte) error
+}
+
+// PcapWriter writes frames to a libpcap file
+type PcapWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewPcapWriter writes the pcap file header to w
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint32(b[0:4], pcapMagic)
+	binary.LittleEndian.PutUint16(b[4:6], 2) /* version 2.4 */
+	binary.LittleEndian.PutUint16(b[6:8], 4)
+	/* 8 - 15 timezone and sigfigs are 0 */
+	binary.LittleEndian.PutUint32(b[16:20], pcapSnapLen)
+	binary.LittleEndian.PutUint32(b[20:24], LINKTYPE_ETHERNET)
+	if _, err := w.Write(b); err != nil {
+		fmt.Println("Error writing pcap header")
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WriteFrame writes a record with the frame and timestamp
+func (p *PcapWriter) WriteFrame(ts time.Time, frame []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b := make([]byte, 16+len(frame))
+	binary.LittleEndian.PutUint32(b[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(len(frame)))
+	copy(b[16:], frame)
+	_, err := p.w.Write(b)
+	return err
+}
+
+// PcapngWriter writes frames to a pcapng file with a
+// single Ethernet interface
+type PcapngWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewPcapngWriter writes the Section Header and Interface
+// Description blocks to w
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	b := make([]byte, 28+20)
+
+	/* Section Header Block */
+	binary.LittleEndian.PutUint32(b[0:4], pcapngSHB)
+	binary.LittleEndian.PutUint32(b[4:8], 28)
+	binary.LittleEndian.PutUint32(b[8:12], pcapngByteOrder)
+	binary.LittleEndian.PutUint16(b[12:14], 1) /* version 1.0 */
+	binary.LittleEndian.PutUint16(b[14:16], 0)
+	/* section length not specified */
+	binary.LittleEndian.PutUint64(b[16:24], 0xffffffffffffffff)
+	binary.LittleEndian.PutUint32(b[24:28], 28)
+
+	/* Interface Description Block, default microsecond resolution */
+	binary.LittleEndian.PutUint32(b[28:32], pcapngIDB)
+	binary.LittleEndian.PutUint32(b[32:36], 20)
+	binary.LittleEndian.PutUint16(b[36:38], LINKTYPE_ETHERNET)
+	binary.LittleEndian.PutUint16(b[38:40], 0)
+	binary.LittleEndian.PutUint32(b[40:44], 0) /* no snap length */
+	binary.LittleEndian.PutUint32(b[44:48], 20)
+
+	if _, err := w.Write(b); err != nil {
+		fmt.Println("Error writing pcapng header")
+		return nil, err
+	}
+	return &PcapngWriter{w: w}, nil
+}
+
+// WriteFrame writes an Enhanced Packet Block with the frame and timestamp
+func (p *PcapngWriter) WriteFrame(ts time.Time, frame []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	padded := (len(frame) + 3) &^ 3
+	blen := 32 + padded
+	b := make([]byte, blen)
+	usec := uint64(ts.UnixNano() / 1000)
+
+	binary.LittleEndian.PutUint32(b[0:4], pcapngEPB)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(blen))
+	binary.LittleEndian.PutUint32(b[8:12], 0) /* interface id */
+	binary.LittleEndian.PutUint32(b[12:16], uint32(usec>>32))
+	binary.LittleEndian.PutUint32(b[16:20], uint32(usec))
+	binary.LittleEndian.PutUint32(b[20:24], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(b[24:28], uint32(len(frame)))
+	copy(b[28:], frame)
+	binary.LittleEndian.PutUint32(b[blen-4:], uint32(blen))
+	_, err := p.w.Write(b)
+	return err
+}
+
+// Record writes the built frame to w with the current time
+func (t *ICMP6) Record(w FrameWriter) error {
+	rErr := errors.New("Error recording frame")
+
+	myFrame, err := t.MarshalBinary()
+	if err != nil {
+		return rErr
+	}
+	if err = w.WriteFrame(time.Now(), myFrame); err != nil {
+		fmt.Println(err)
+		return rErr
+	}
+	return nil
+}
+
+// Link Types PcapReader passes on besides LINKTYPE_ETHERNET
+const (
+	LINKTYPE_RAW  = 101
+	LINKTYPE_IPV6 = 228
+)
+
+// pcapng Simple Packet Block
+const pcapngSPB = 0x00000003
+
+// PcapReader reads frames from a libpcap or pcapng file
+type PcapReader struct {
+	r     io.Reader
+	ng    bool
+	order binary.ByteOrder
+
+	// pcap
+	nsec     bool
+	linkType int
+
+	// pcapng link type and timestamp units per second by interface
+	ifaces []pcapngIface
+}
+
+type pcapngIface struct {
+	linkType int
+	tsUnits  uint64
+}
+
+// NewPcapReader reads the file header from r. pcap and pcapng
+// files in either byte order are detected by their magic number
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	rErr := errors.New("Error reading capture file")
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		fmt.Println("Error reading capture header", err)
+		return nil, rErr
+	}
+	p := &PcapReader{r: r}
+
+	if binary.BigEndian.Uint32(magic) == pcapngSHB {
+		p.ng = true
+		if err := p.readSHB(); err != nil {
+			fmt.Println(err)
+			return nil, rErr
+		}
+		return p, nil
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(magic) == pcapMagic:
+		p.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic) == pcapMagic:
+		p.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == pcapMagicNsec:
+		p.order = binary.LittleEndian
+		p.nsec = true
+	case binary.BigEndian.Uint32(magic) == pcapMagicNsec:
+		p.order = binary.BigEndian
+		p.nsec = true
+	default:
+		fmt.Println("Not a pcap or pcapng file")
+		return nil, rErr
+	}
+	b := make([]byte, 20)
+	if _, err := io.ReadFull(r, b); err != nil {
+		fmt.Println("Error reading pcap header", err)
+		return nil, rErr
+	}
+	p.linkType = int(p.order.Uint32(b[16:20]))
+	return p, nil
+}
+
+// ReadFrame returns the timestamp and bytes of the next Ethernet or
+// IPv6 frame. Frames of other link types are skipped. Returns io.EOF
+// at the end of the file
+func (p *PcapReader) ReadFrame() (time.Time, []byte, error) {
+	for {
+		var ts time.Time
+		var frame []byte
+		var linkType int
+		var err error
+
+		if p.ng {
+			ts, frame, linkType, err = p.readNgPacket()
+		} else {
+			ts, frame, err = p.readPacket()
+			linkType = p.linkType
+		}
+		if err != nil {
+			return ts, nil, err
+		}
+		switch linkType {
+		case LINKTYPE_ETHERNET, LINKTYPE_RAW, LINKTYPE_IPV6:
+			return ts, frame, nil
+		}
+	}
+}
+
+// next pcap record
+func (p *PcapReader) readPacket() (time.Time, []byte, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, hdr); err != nil {
+		return time.Time{}, nil, err
+	}
+	sec := int64(p.order.Uint32(hdr[0:4]))
+	frac := int64(p.order.Uint32(hdr[4:8]))
+	if !p.nsec {
+		frac *= 1000
+	}
+	caplen := p.order.Uint32(hdr[8:12])
+	if caplen > 0x40000 {
+		return time.Time{}, nil, errors.New("pcap record too large")
+	}
+	frame := make([]byte, caplen)
+	if _, err := io.ReadFull(p.r, frame); err != nil {
+		return time.Time{}, nil, io.ErrUnexpectedEOF
+	}
+	return time.Unix(sec, frac), frame, nil
+}
+
+// next pcapng packet block, handling the blocks in between
+func (p *PcapReader) readNgPacket() (time.Time, []byte, int, error) {
+	for {
+		hdr := make([]byte, 4)
+		if _, err := io.ReadFull(p.r, hdr); err != nil {
+			return time.Time{}, nil, 0, err
+		}
+		// a new section may change the byte order
+		if binary.BigEndian.Uint32(hdr) == pcapngSHB {
+			if err := p.readSHB(); err != nil {
+				return time.Time{}, nil, 0, err
+			}
+			continue
+		}
+		btype := p.order.Uint32(hdr)
+		body, err := p.readBlockBody()
+		if err != nil {
+			return time.Time{}, nil, 0, err
+		}
+
+		switch btype {
+		case pcapngIDB:
+			if len(body) < 8 {
+				return time.Time{}, nil, 0, errors.New("short interface block")
+			}
+			iface := pcapngIface{
+				linkType: int(p.order.Uint16(body[0:2])),
+				tsUnits:  1000000,
+			}
+			p.parseIDBOptions(&iface, body[8:])
+			p.ifaces = append(p.ifaces, iface)
+		case pcapngEPB:
+			if len(body) < 20 {
+				return time.Time{}, nil, 0, errors.New("short packet block")
+			}
+			id := int(p.order.Uint32(body[0:4]))
+			if id >= len(p.ifaces) {
+				return time.Time{}, nil, 0, errors.New("unknown pcapng interface")
+			}
+			caplen := int(p.order.Uint32(body[12:16]))
+			if 20+caplen > len(body) {
+				return time.Time{}, nil, 0, errors.New("short packet block")
+			}
+			tsval := uint64(p.order.Uint32(body[4:8]))<<32 | uint64(p.order.Uint32(body[8:12]))
+			units := p.ifaces[id].tsUnits
+			ts := time.Unix(int64(tsval/units), int64((tsval%units)*1000000000/units))
+			return ts, body[20 : 20+caplen], p.ifaces[id].linkType, nil
+		case pcapngSPB:
+			if len(body) < 4 || len(p.ifaces) == 0 {
+				return time.Time{}, nil, 0, errors.New("bad simple packet block")
+			}
+			caplen := int(p.order.Uint32(body[0:4]))
+			if 4+caplen > len(body) {
+				caplen = len(body) - 4
+			}
+			return time.Time{}, body[4 : 4+caplen], p.ifaces[0].linkType, nil
+		}
+	}
+}
+
+// Section Header Block after the block type was read.
+// Sets the byte order and forgets the interfaces
+func (p *PcapReader) readSHB() error {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		return err
+	}
+	switch {
+	case binary.LittleEndian.Uint32(b[4:8]) == pcapngByteOrder:
+		p.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(b[4:8]) == pcapngByteOrder:
+		p.order = binary.BigEndian
+	default:
+		return errors.New("bad pcapng byte order magic")
+	}
+	blen := int(p.order.Uint32(b[0:4]))
+	if blen < 28 || blen%4 != 0 {
+		return errors.New("bad pcapng section header")
+	}
+	// skip the rest of the block
+	if _, err := io.CopyN(io.Discard, p.r, int64(blen-12)); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	p.ifaces = nil
+	return nil
+}
+
+// rest of a block after the block type. Returns the block body
+// without the length fields
+func (p *PcapReader) readBlockBody() ([]byte, error) {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	blen := int(p.order.Uint32(b))
+	if blen < 12 || blen%4 != 0 || blen > 0x40000 {
+		return nil, errors.New("bad pcapng block length")
+	}
+	body := make([]byte, blen-8)
+	if _, err := io.ReadFull(p.r, body); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body[:blen-12], nil
+}
+
+// look for if_tsresol in the Interface Description Block options
+func (p *PcapReader) parseIDBOptions(iface *pcapngIface, b []byte) {
+	for len(b) >= 4 {
+		code := p.order.Uint16(b[0:2])
+		olen := int(p.order.Uint16(b[2:4]))
+		if code == 0 || 4+olen > len(b) {
+			return
+		}
+		if code == 9 && olen >= 1 {
+			res := b[4]
+			units := uint64(1)
+			for i := 0; i < int(res&0x7f); i++ {
+				if res&0x80 != 0 {
+					units *= 2
+				} else {
+					units *= 10
+				}
+			}
+			if units > 1 {
+				iface.tsUnits = units
+			}
+		}
+		b = b[4+((olen+3)&^3):]
+	}
+}
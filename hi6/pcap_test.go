@@ -0,0 +1,177 @@
+package hi6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := []byte{1, 2, 3, 4, 5}
+	ts := time.Unix(1000, 2000)
+	if err := w.WriteFrame(ts, frame); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != 24+16+len(frame) {
+		t.Fatalf("file length %d", len(b))
+	}
+	le := binary.LittleEndian
+	if le.Uint32(b[0:4]) != pcapMagic || le.Uint32(b[20:24]) != LINKTYPE_ETHERNET {
+		t.Fatalf("header % x", b[:24])
+	}
+	rec := b[24:]
+	if le.Uint32(rec[0:4]) != 1000 || le.Uint32(rec[4:8]) != 2 || le.Uint32(rec[8:12]) != 5 || le.Uint32(rec[12:16]) != 5 {
+		t.Fatalf("record header % x", rec[:16])
+	}
+	if !bytes.Equal(rec[16:], frame) {
+		t.Fatalf("frame % x", rec[16:])
+	}
+}
+
+func TestPcapngWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPcapngWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := []byte{1, 2, 3, 4, 5}
+	if err := w.WriteFrame(time.Unix(1, 0), frame); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	le := binary.LittleEndian
+	// every block starts and ends with the same length
+	var types []uint32
+	for len(b) > 0 {
+		blen := int(le.Uint32(b[4:8]))
+		if blen%4 != 0 || blen > len(b) || le.Uint32(b[blen-4:blen]) != uint32(blen) {
+			t.Fatalf("bad block % x", b)
+		}
+		types = append(types, le.Uint32(b[0:4]))
+		if le.Uint32(b[0:4]) == pcapngEPB {
+			if le.Uint32(b[16:20]) != 1000000 || !bytes.Equal(b[28:33], frame) {
+				t.Fatalf("packet block % x", b[:blen])
+			}
+		}
+		b = b[blen:]
+	}
+	if len(types) != 3 || types[0] != pcapngSHB || types[1] != pcapngIDB || types[2] != pcapngEPB {
+		t.Fatalf("blocks %x", types)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewPcapWriter(&buf)
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55", Type: ICMPTypeEchoRequest}
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Record(w); err != nil {
+		t.Fatal(err)
+	}
+	frame, _ := p.MarshalBinary()
+	if !bytes.Equal(buf.Bytes()[40:], frame) {
+		t.Fatal("recorded frame differs")
+	}
+}
+
+func TestPcapReader(t *testing.T) {
+	frames := [][]byte{{1, 2, 3}, {4, 5, 6, 7, 8}}
+	ts := time.Unix(1500, 3000)
+
+	var pcap, ng bytes.Buffer
+	pw, _ := NewPcapWriter(&pcap)
+	nw, _ := NewPcapngWriter(&ng)
+	for _, f := range frames {
+		pw.WriteFrame(ts, f)
+		nw.WriteFrame(ts, f)
+	}
+
+	for name, buf := range map[string]*bytes.Buffer{"pcap": &pcap, "pcapng": &ng} {
+		r, err := NewPcapReader(buf)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		for _, f := range frames {
+			rts, rf, err := r.ReadFrame()
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if !rts.Equal(ts) || !bytes.Equal(rf, f) {
+				t.Fatalf("%s: got %v % x", name, rts, rf)
+			}
+		}
+		if _, _, err := r.ReadFrame(); err != io.EOF {
+			t.Fatalf("%s: expected EOF, got %v", name, err)
+		}
+	}
+}
+
+func TestPcapReaderBigEndianNsec(t *testing.T) {
+	be := binary.BigEndian
+	b := make([]byte, 24+16+2)
+	be.PutUint32(b[0:4], pcapMagicNsec)
+	be.PutUint32(b[20:24], LINKTYPE_IPV6)
+	be.PutUint32(b[24:28], 7)
+	be.PutUint32(b[28:32], 9)
+	be.PutUint32(b[32:36], 2)
+	be.PutUint32(b[36:40], 2)
+	b[40], b[41] = 0x60, 0x01
+
+	r, err := NewPcapReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, f, err := r.ReadFrame()
+	if err != nil || !ts.Equal(time.Unix(7, 9)) || !bytes.Equal(f, []byte{0x60, 0x01}) {
+		t.Fatalf("%v %v % x", err, ts, f)
+	}
+}
+
+func TestPcapReaderNotCapture(t *testing.T) {
+	if _, err := NewPcapReader(bytes.NewReader([]byte("not a capture"))); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeEchoRequest, ICMP6_id: 7, ICMP6_seq: 1}
+	q := roundTrip(t, &p)
+
+	r := Replay{Iface: "lo", SrcIP: "2001:db8::a", DstIP: "2001:db8::b", Types: []ICMPType{ICMPTypeEchoRequest}}
+	if !r.match(q) {
+		t.Fatal("echo request not matched")
+	}
+	if err := r.rewrite(q); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := q.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same packet built with the new addresses
+	p.SrcIP, p.DstIP = r.SrcIP, r.DstIP
+	if err := p.BuildICMPPacket(); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := p.MarshalBinary()
+	if !bytes.Equal(frame, want) {
+		t.Fatalf("rewritten\n% x\nbuilt\n% x", frame, want)
+	}
+
+	r.Types = []ICMPType{ICMPTypeEchoReply}
+	if r.match(q) {
+		t.Fatal("echo request matched echo reply filter")
+	}
+}
@@ -0,0 +1,128 @@
+package hi6
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Fields of the invoking packet a Parameter Problem can point to
+const (
+	PP_FIELD_NEXT_HEADER    = 1 /* Next Header */
+	PP_FIELD_LENGTH         = 2 /* Payload Length or Hdr Ext Len */
+	PP_FIELD_OPTION         = 3 /* option type at Offset */
+	PP_FIELD_OFFSET         = 4 /* any byte at Offset */
+	PP_FIELD_FIRST_FRAGMENT = 5 /* first fragment missing upper layer header */
+)
+
+// FieldRef names a field of the invoking packet by header and field
+// rather than by byte offset
+type FieldRef struct {
+	// Header in the invoking packet. 0 is the IPv6 header,
+	// 1 the first Extension Header and so on
+	Header int
+
+	// Field (ie PP_FIELD_NEXT_HEADER, PP_FIELD_OPTION)
+	Field int
+
+	// Offset from the start of the header for PP_FIELD_OPTION
+	// and PP_FIELD_OFFSET
+	Offset int
+}
+
+// ParameterProblemPointer returns the Pointer and Code of a Parameter
+// Problem for field ref of the invoking packet pkt. A Next Header
+// gives Unrecognized Next Header, an option Unrecognized Option and
+// PP_FIELD_FIRST_FRAGMENT the RFC 7112 code with Pointer 0. An
+// Offset outside the header or the packet is an error
+func ParameterProblemPointer(pkt []byte, ref FieldRef) (uint32, int, error) {
+	pErr := errors.New("Error finding Parameter Problem pointer")
+
+	if ref.Field == PP_FIELD_FIRST_FRAGMENT {
+		return 0, PP_FIRST_FRAG_INCOMPLETE, nil
+	}
+	if len(pkt) < IPHeaderLen {
+		fmt.Println("Parameter Problem: invoking packet too short")
+		return 0, 0, pErr
+	}
+
+	starts, ends := headerOffsets(pkt)
+	if ref.Header < 0 || ref.Header >= len(starts) {
+		fmt.Println("Parameter Problem: invoking packet has no header", ref.Header)
+		return 0, 0, pErr
+	}
+	start := starts[ref.Header]
+
+	if ref.Field == PP_FIELD_OPTION || ref.Field == PP_FIELD_OFFSET {
+		end := ends[ref.Header]
+		if end > len(pkt) {
+			end = len(pkt)
+		}
+		if ref.Offset < 0 || start+ref.Offset >= end {
+			fmt.Println("Parameter Problem: offset", ref.Offset, "outside header", ref.Header)
+			return 0, 0, pErr
+		}
+	}
+
+	switch ref.Field {
+	case PP_FIELD_NEXT_HEADER:
+		if ref.Header == 0 {
+			return 6, PP_NEXT_HEADER, nil
+		}
+		return uint32(start), PP_NEXT_HEADER, nil
+	case PP_FIELD_LENGTH:
+		if ref.Header == 0 {
+			return 4, PP_HEADER_FIELD, nil
+		}
+		return uint32(start + 1), PP_HEADER_FIELD, nil
+	case PP_FIELD_OPTION:
+		return uint32(start + ref.Offset), PP_OPTION, nil
+	case PP_FIELD_OFFSET:
+		return uint32(start + ref.Offset), PP_HEADER_FIELD, nil
+	}
+	fmt.Println("Parameter Problem: unknown field", ref.Field)
+	return 0, 0, pErr
+}
+
+// start and end offsets of the IPv6 header and each Extension
+// Header in pkt. A truncated header may end past len(pkt)
+func headerOffsets(pkt []byte) ([]int, []int) {
+	starts := []int{0}
+	ends := []int{IPHeaderLen}
+	nh := int(pkt[6])
+	off := IPHeaderLen
+	for off+8 <= len(pkt) {
+		l := 0
+		switch nh {
+		case EXT_HOPBYHOP, EXT_ROUTING, EXT_DSTOPTS:
+			l = (int(pkt[off+1]) + 1) * 8
+		case EXT_FRAGMENT:
+			l = 8
+		default:
+			return starts, ends
+		}
+		starts = append(starts, off)
+		ends = append(ends, off+l)
+		nh = int(pkt[off])
+		off += l
+	}
+	return starts, ends
+}
+
+// fill in ICMP6_pptr and Code from PP_Field
+func (t *ICMP6) setParameterProblem() error {
+	inv := t.InvokingData
+	if t.Invoking != nil {
+		var err error
+		inv, err = t.Invoking.Marshal()
+		if err != nil {
+			return err
+		}
+	}
+	ptr, code, err := ParameterProblemPointer(inv, *t.PP_Field)
+	if err != nil {
+		return err
+	}
+	t.ICMP6_pptr = ptr
+	t.Code = code
+	return nil
+}
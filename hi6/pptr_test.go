@@ -0,0 +1,70 @@
+package hi6
+
+import (
+	"syscall"
+	"testing"
+)
+
+func pptrPacket(t *testing.T) []byte {
+	inv := InvokingPacket{SrcIP: "2001:db8::1", DstIP: "2001:db8::2", Proto: syscall.IPPROTO_UDP, SrcPort: 1, DstPort: 2}
+	inv.ExtHeaders = []ExtHeader{
+		{Type: EXT_HOPBYHOP, Options: []ExtOption{{Type: 0x3e, Data: []byte{1, 2}}}},
+		{Type: EXT_DSTOPTS, Options: []ExtOption{{Type: 0x9e, Data: make([]byte, 8)}}},
+	}
+	b, err := inv.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParameterProblemPointer(t *testing.T) {
+	pkt := pptrPacket(t)
+	tests := []struct {
+		ref  FieldRef
+		ptr  uint32
+		code int
+	}{
+		{FieldRef{Header: 0, Field: PP_FIELD_NEXT_HEADER}, 6, PP_NEXT_HEADER},
+		{FieldRef{Header: 0, Field: PP_FIELD_LENGTH}, 4, PP_HEADER_FIELD},
+		{FieldRef{Header: 1, Field: PP_FIELD_OPTION, Offset: 2}, 42, PP_OPTION},
+		{FieldRef{Header: 2, Field: PP_FIELD_LENGTH}, 49, PP_HEADER_FIELD},
+		{FieldRef{Header: 2, Field: PP_FIELD_OFFSET, Offset: 15}, 63, PP_HEADER_FIELD},
+		{FieldRef{Field: PP_FIELD_FIRST_FRAGMENT}, 0, PP_FIRST_FRAG_INCOMPLETE},
+	}
+	for _, tt := range tests {
+		ptr, code, err := ParameterProblemPointer(pkt, tt.ref)
+		if err != nil || ptr != tt.ptr || code != tt.code {
+			t.Errorf("%+v: got %d %d %v", tt.ref, ptr, code, err)
+		}
+	}
+}
+
+func TestParameterProblemPointerRange(t *testing.T) {
+	pkt := pptrPacket(t)
+	for _, ref := range []FieldRef{
+		{Header: 3, Field: PP_FIELD_NEXT_HEADER},
+		{Header: 0, Field: PP_FIELD_OFFSET, Offset: 40},
+		{Header: 1, Field: PP_FIELD_OPTION, Offset: 8},
+		{Header: 2, Field: PP_FIELD_OFFSET, Offset: -1},
+		{Header: 2, Field: PP_FIELD_OFFSET, Offset: 16},
+	} {
+		if _, _, err := ParameterProblemPointer(pkt, ref); err == nil {
+			t.Errorf("%+v: expected error", ref)
+		}
+	}
+	// past the end of a truncated packet
+	if _, _, err := ParameterProblemPointer(pkt[:58], FieldRef{Header: 2, Field: PP_FIELD_OFFSET, Offset: 12}); err == nil {
+		t.Error("expected error past the end of the packet")
+	}
+}
+
+func TestParseParameterProblem(t *testing.T) {
+	p := ICMP6{Iface: "lo", DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeParameterProblem, InvokingData: pptrPacket(t),
+		PP_Field: &FieldRef{Header: 1, Field: PP_FIELD_OPTION, Offset: 2}}
+	q := roundTrip(t, &p)
+	if q.Code != PP_OPTION || q.ICMP6_pptr != 42 || q.Invoking == nil || len(q.Invoking.ExtHeaders) != 2 {
+		t.Fatalf("%+v", q)
+	}
+}
@@ -0,0 +1,174 @@
+package hi6
+
+import (
+	"errors"
+	"fmt"
+	"github.com/songgao/ether"
+	"github.com/songgao/packets/ethernet"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Replay sends the ICMP6 frames of a capture file out an interface,
+// optionally with its own addressing
+type Replay struct {
+	// Interface to send frames on
+	Iface string
+
+	// If set, replaces the addresses of every replayed frame.
+	// Source MAC and Destination MAC must be set when the
+	// capture holds bare IPv6 packets
+	SrcMAC string
+	DstMAC string
+	SrcIP  string
+	DstIP  string
+
+	// Only replay these ICMP Types. If empty replay all types
+	Types []ICMPType
+
+	// Wait between frames. If zero and KeepTiming is false
+	// frames are sent as fast as possible
+	Interval time.Duration
+
+	// Set to true to keep the gaps between frames from the capture
+	KeepTiming bool
+
+	// If set, every replayed frame is recorded here
+	Capture FrameWriter
+}
+
+// Run replays every ICMP6 frame read from r and returns the
+// number of frames sent. Frames that are not ICMP6 are skipped
+func (r *Replay) Run(pr *PcapReader) (int, error) {
+	rErr := errors.New("Error replaying capture")
+
+	hwIface, err := net.InterfaceByName(r.Iface)
+	if err != nil {
+		fmt.Println(err)
+		return 0, rErr
+	}
+	// we only write, so drop everything that comes in
+	ff := func(frame ethernet.Frame) bool { return false }
+	myDev, err := ether.NewDev(hwIface, ff)
+	if err != nil {
+		fmt.Println("Error getting interface", err)
+		return 0, rErr
+	}
+	defer myDev.Close()
+
+	sent := 0
+	var last time.Time
+	for {
+		ts, frame, err := pr.ReadFrame()
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			fmt.Println(err)
+			return sent, rErr
+		}
+
+		t := new(ICMP6)
+		if err := t.unmarshal(frame); err != nil {
+			continue
+		}
+		if !r.match(t) {
+			continue
+		}
+		if err := r.rewrite(t); err != nil {
+			return sent, rErr
+		}
+		myFrame, err := t.MarshalBinary()
+		if err != nil {
+			return sent, rErr
+		}
+
+		if r.KeepTiming && !last.IsZero() && ts.After(last) {
+			time.Sleep(ts.Sub(last))
+		} else if sent > 0 {
+			time.Sleep(r.Interval)
+		}
+		last = ts
+
+		if err = myDev.Write(myFrame); err != nil {
+			fmt.Println("Error write frame")
+			return sent, rErr
+		}
+		if r.Capture != nil {
+			if err = t.Record(r.Capture); err != nil {
+				return sent, rErr
+			}
+		}
+		sent++
+	}
+}
+
+// check the packet against Types
+func (r *Replay) match(t *ICMP6) bool {
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, typ := range r.Types {
+		if typ == t.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// apply the replay addresses
+func (r *Replay) rewrite(t *ICMP6) error {
+	t.Iface = r.Iface
+	if r.SrcMAC != "" {
+		t.SrcMAC = r.SrcMAC
+	}
+	if r.DstMAC != "" {
+		t.DstMAC = r.DstMAC
+	}
+	if r.SrcIP == "" && r.DstIP == "" {
+		return nil
+	}
+	if r.SrcIP != "" {
+		t.SrcIP = r.SrcIP
+	}
+	if r.DstIP != "" {
+		t.DstIP = r.DstIP
+	}
+	return t.Rewrite()
+}
+
+// Rewrite writes SrcIP and DstIP into a packet decoded by Parse and
+// recomputes the ICMP6 checksum. SrcMAC and DstMAC are applied by Send
+func (t *ICMP6) Rewrite() error {
+	rErr := errors.New("Error rewriting packet")
+
+	if len(t.frame) < IPHeaderLen {
+		fmt.Println("Must parse or build a packet first!")
+		return rErr
+	}
+	src := net.ParseIP(t.SrcIP)
+	dst := net.ParseIP(t.DstIP)
+	if src == nil || dst == nil {
+		fmt.Println("Rewrite: could not parse IP addresses")
+		return rErr
+	}
+	copy(t.frame[8:24], src.To16())
+	copy(t.frame[24:40], dst.To16())
+
+	nh, off, exts, err := walkExtHeaders(t.frame)
+	if err != nil || nh != syscall.IPPROTO_ICMPV6 || len(t.frame) < off+icmpMinLen {
+		fmt.Println("Rewrite: could not find ICMP6 header")
+		return rErr
+	}
+	msg := t.frame[off:]
+	msg[2] = 0
+	msg[3] = 0
+	cs := pseudoCsum(src, finalDst(exts, dst), syscall.IPPROTO_ICMPV6, msg)
+	msg[2] = byte(cs)
+	msg[3] = byte(cs >> 8)
+	t.Checksum = uint16(msg[2])<<8 | uint16(msg[3])
+	t.ChecksumValid = true
+	return nil
+}
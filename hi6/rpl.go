@@ -0,0 +1,366 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// RPL Control Codes
+const (
+	RPL_DIS     = 0x00
+	RPL_DIO     = 0x01
+	RPL_DAO     = 0x02
+	RPL_DAO_ACK = 0x03
+)
+
+// RPL Control Option Types
+const (
+	RPL_OPT_PAD1           = 0
+	RPL_OPT_PADN           = 1
+	RPL_OPT_DAG_METRIC     = 2
+	RPL_OPT_ROUTE_INFO     = 3
+	RPL_OPT_DODAG_CONFIG   = 4
+	RPL_OPT_TARGET         = 5
+	RPL_OPT_TRANSIT        = 6
+	RPL_OPT_SOLICITED_INFO = 7
+	RPL_OPT_PREFIX_INFO    = 8
+	RPL_OPT_TARGET_DESC    = 9
+)
+
+// RPL DIO Mode of Operation
+const (
+	RPL_MOP_NO_DOWNWARD   = 0
+	RPL_MOP_NON_STORING   = 1
+	RPL_MOP_STORING       = 2
+	RPL_MOP_STORING_MCAST = 3
+)
+
+// RPL Solicited Information Flags
+const (
+	RPL_SI_FLAG_VERSION  = 0x80
+	RPL_SI_FLAG_INSTANCE = 0x40
+	RPL_SI_FLAG_DODAGID  = 0x20
+)
+
+// RPL Control flags and lengths
+const (
+	rplDIOGrounded     = 0x80
+	rplDAOAckReq       = 0x80
+	rplDAODODAGID      = 0x40
+	rplDAOAckDODAGID   = 0x80
+	rplDISLen          = 2
+	rplDIOLen          = 24
+	rplDAOLen          = 4
+	rplDODAGConfigLen  = 14
+	rplTransitLen      = 4
+	rplSolicitedLen    = 19
+	rplPrefixInfoLen   = 30
+	rplConfigAuth      = 0x08
+	rplConfigPCSMask   = 0x07
+	rplTransitExternal = 0x80
+)
+
+// RPLOption is a RPL Control Message Option. These are not ND
+// options: the Length byte counts bytes and Pad1 has no Length
+type RPLOption struct {
+	// Option Type (ie RPL_OPT_DODAG_CONFIG)
+	Type int
+
+	// Raw option bytes including type and length. Used for
+	// options hi6 does not know how to build or decode (ie PadN)
+	Raw []byte
+
+	// DODAG Configuration
+	DC_Auth               bool
+	DC_PCS                int
+	DC_DIOIntDoubl        int
+	DC_DIOIntMin          int
+	DC_DIORedun           int
+	DC_MaxRankIncrease    int
+	DC_MinHopRankIncrease int
+	DC_OCP                int
+	DC_DefLifetime        int
+	DC_LifetimeUnit       int
+
+	// RPL Target and Prefix Information Prefix and Prefix Length
+	Prefix    string
+	PrefixLen int
+
+	// Prefix Information Flags (ie OPT_FLAG_ONLINK) and Lifetimes
+	PI_Flags      byte
+	PI_Valid_Time uint32
+	PI_Pref_Time  uint32
+
+	// Transit Information. TI_Parent is optional
+	TI_External     bool
+	TI_PathControl  int
+	TI_PathSeq      int
+	TI_PathLifetime int
+	TI_Parent       string
+
+	// Solicited Information
+	SI_InstanceID int
+	SI_Flags      int
+	SI_DODAGID    string
+	SI_Version    int
+}
+
+// Convenience function to add RPL Control Options to ICMP6 struct
+func (t *ICMP6) AddRPLOption(o RPLOption) {
+	t.RPL_Options = append(t.RPL_Options, o)
+}
+
+// RPL Control message base and options. The first 4 bytes
+// go in the ICMP6 header data
+func (t *ICMP6) rplBody() ([]byte, error) {
+	rErr := errors.New("Error building RPL Control message")
+
+	var b []byte
+	switch t.Code {
+	case RPL_DIS:
+		b = make([]byte, rplDISLen)
+	case RPL_DIO:
+		b = make([]byte, rplDIOLen)
+		b[0] = byte(t.RPL_InstanceID)
+		b[1] = byte(t.RPL_Version)
+		binary.BigEndian.PutUint16(b[2:4], uint16(t.RPL_Rank))
+		b[4] = byte(t.RPL_MOP&0x07)<<3 | byte(t.RPL_Prf&0x07)
+		if t.RPL_Grounded {
+			b[4] |= rplDIOGrounded
+		}
+		b[5] = byte(t.RPL_DTSN)
+		ip := net.ParseIP(t.RPL_DODAGID)
+		if ip == nil {
+			fmt.Println("RPL: could not parse DODAGID")
+			return nil, rErr
+		}
+		copy(b[8:24], ip.To16())
+	case RPL_DAO, RPL_DAO_ACK:
+		b = make([]byte, rplDAOLen)
+		b[0] = byte(t.RPL_InstanceID)
+		if t.Code == RPL_DAO {
+			if t.RPL_AckReq {
+				b[1] |= rplDAOAckReq
+			}
+			b[3] = byte(t.RPL_DAOSeq)
+		} else {
+			b[2] = byte(t.RPL_DAOSeq)
+			b[3] = byte(t.RPL_Status)
+		}
+		if t.RPL_DODAGID != "" {
+			ip := net.ParseIP(t.RPL_DODAGID)
+			if ip == nil {
+				fmt.Println("RPL: could not parse DODAGID")
+				return nil, rErr
+			}
+			if t.Code == RPL_DAO {
+				b[1] |= rplDAODODAGID
+			} else {
+				b[1] |= rplDAOAckDODAGID
+			}
+			b = append(b, ip.To16()...)
+		}
+	default:
+		fmt.Println("RPL: unsupported Code", t.Code)
+		return nil, rErr
+	}
+
+	for _, o := range t.RPL_Options {
+		ob, err := o.marshal()
+		if err != nil {
+			return nil, rErr
+		}
+		b = append(b, ob...)
+	}
+	return b, nil
+}
+
+// marshal returns the binary encoding of a RPL option
+func (o *RPLOption) marshal() ([]byte, error) {
+	oErr := errors.New("Error building RPL option")
+
+	if o.Raw != nil {
+		return o.Raw, nil
+	}
+	var b []byte
+	switch o.Type {
+	case RPL_OPT_PAD1:
+		return []byte{RPL_OPT_PAD1}, nil
+	case RPL_OPT_DODAG_CONFIG:
+		b = make([]byte, rplDODAGConfigLen)
+		b[0] = byte(o.DC_PCS) & rplConfigPCSMask
+		if o.DC_Auth {
+			b[0] |= rplConfigAuth
+		}
+		b[1] = byte(o.DC_DIOIntDoubl)
+		b[2] = byte(o.DC_DIOIntMin)
+		b[3] = byte(o.DC_DIORedun)
+		binary.BigEndian.PutUint16(b[4:6], uint16(o.DC_MaxRankIncrease))
+		binary.BigEndian.PutUint16(b[6:8], uint16(o.DC_MinHopRankIncrease))
+		binary.BigEndian.PutUint16(b[8:10], uint16(o.DC_OCP))
+		b[11] = byte(o.DC_DefLifetime)
+		binary.BigEndian.PutUint16(b[12:14], uint16(o.DC_LifetimeUnit))
+	case RPL_OPT_TARGET:
+		ip := net.ParseIP(o.Prefix)
+		if ip == nil || o.PrefixLen > 128 {
+			fmt.Println("RPL: could not parse target prefix")
+			return nil, oErr
+		}
+		b = []byte{0, byte(o.PrefixLen)}
+		b = append(b, ip.To16()[:(o.PrefixLen+7)/8]...)
+	case RPL_OPT_TRANSIT:
+		b = make([]byte, rplTransitLen)
+		if o.TI_External {
+			b[0] = rplTransitExternal
+		}
+		b[1] = byte(o.TI_PathControl)
+		b[2] = byte(o.TI_PathSeq)
+		b[3] = byte(o.TI_PathLifetime)
+		if o.TI_Parent != "" {
+			ip := net.ParseIP(o.TI_Parent)
+			if ip == nil {
+				fmt.Println("RPL: could not parse parent address")
+				return nil, oErr
+			}
+			b = append(b, ip.To16()...)
+		}
+	case RPL_OPT_SOLICITED_INFO:
+		b = make([]byte, rplSolicitedLen)
+		b[0] = byte(o.SI_InstanceID)
+		b[1] = byte(o.SI_Flags)
+		if o.SI_DODAGID != "" {
+			ip := net.ParseIP(o.SI_DODAGID)
+			if ip == nil {
+				fmt.Println("RPL: could not parse solicited DODAGID")
+				return nil, oErr
+			}
+			copy(b[2:18], ip.To16())
+		}
+		b[18] = byte(o.SI_Version)
+	case RPL_OPT_PREFIX_INFO:
+		b = make([]byte, rplPrefixInfoLen)
+		b[0] = byte(o.PrefixLen)
+		b[1] = o.PI_Flags
+		binary.BigEndian.PutUint32(b[2:6], o.PI_Valid_Time)
+		binary.BigEndian.PutUint32(b[6:10], o.PI_Pref_Time)
+		ip := net.ParseIP(o.Prefix)
+		if ip == nil {
+			fmt.Println("RPL: could not parse prefix")
+			return nil, oErr
+		}
+		copy(b[14:30], ip.To16())
+	default:
+		fmt.Println("Unknown RPL option type", o.Type, "needs Raw")
+		return nil, oErr
+	}
+	return append([]byte{byte(o.Type), byte(len(b))}, b...), nil
+}
+
+// decode a RPL Control message. b is the ICMP6 header
+// data followed by the body
+func (t *ICMP6) parseRPL(b []byte) []byte {
+	switch t.Code {
+	case RPL_DIS:
+		if len(b) < rplDISLen {
+			return b
+		}
+		b = b[rplDISLen:]
+	case RPL_DIO:
+		if len(b) < rplDIOLen {
+			return b
+		}
+		t.RPL_InstanceID = int(b[0])
+		t.RPL_Version = int(b[1])
+		t.RPL_Rank = int(binary.BigEndian.Uint16(b[2:4]))
+		t.RPL_Grounded = b[4]&rplDIOGrounded != 0
+		t.RPL_MOP = int(b[4]>>3) & 0x07
+		t.RPL_Prf = int(b[4]) & 0x07
+		t.RPL_DTSN = int(b[5])
+		t.RPL_DODAGID = net.IP(b[8:24]).String()
+		b = b[rplDIOLen:]
+	case RPL_DAO, RPL_DAO_ACK:
+		t.RPL_InstanceID = int(b[0])
+		d := b[1]&rplDAOAckDODAGID != 0
+		if t.Code == RPL_DAO {
+			t.RPL_AckReq = b[1]&rplDAOAckReq != 0
+			d = b[1]&rplDAODODAGID != 0
+			t.RPL_DAOSeq = int(b[3])
+		} else {
+			t.RPL_DAOSeq = int(b[2])
+			t.RPL_Status = int(b[3])
+		}
+		b = b[rplDAOLen:]
+		if d {
+			if len(b) < 16 {
+				return b
+			}
+			t.RPL_DODAGID = net.IP(b[0:16]).String()
+			b = b[16:]
+		}
+	default:
+		// secured messages are kept as Data
+		return b
+	}
+
+	for len(b) > 0 {
+		if b[0] == RPL_OPT_PAD1 {
+			t.AddRPLOption(RPLOption{Type: RPL_OPT_PAD1})
+			b = b[1:]
+			continue
+		}
+		if len(b) < 2 || 2+int(b[1]) > len(b) {
+			return b
+		}
+		t.AddRPLOption(parseRPLOption(b[:2+int(b[1])]))
+		b = b[2+int(b[1]):]
+	}
+	return nil
+}
+
+// decode a RPL option. b holds the whole option
+func parseRPLOption(b []byte) RPLOption {
+	o := RPLOption{Type: int(b[0])}
+	d := b[2:]
+	switch {
+	case o.Type == RPL_OPT_DODAG_CONFIG && len(d) >= rplDODAGConfigLen:
+		o.DC_Auth = d[0]&rplConfigAuth != 0
+		o.DC_PCS = int(d[0] & rplConfigPCSMask)
+		o.DC_DIOIntDoubl = int(d[1])
+		o.DC_DIOIntMin = int(d[2])
+		o.DC_DIORedun = int(d[3])
+		o.DC_MaxRankIncrease = int(binary.BigEndian.Uint16(d[4:6]))
+		o.DC_MinHopRankIncrease = int(binary.BigEndian.Uint16(d[6:8]))
+		o.DC_OCP = int(binary.BigEndian.Uint16(d[8:10]))
+		o.DC_DefLifetime = int(d[11])
+		o.DC_LifetimeUnit = int(binary.BigEndian.Uint16(d[12:14]))
+	case o.Type == RPL_OPT_TARGET && len(d) >= 2 && len(d)-2 <= 16:
+		o.PrefixLen = int(d[1])
+		ip := make(net.IP, 16)
+		copy(ip, d[2:])
+		o.Prefix = ip.String()
+	case o.Type == RPL_OPT_TRANSIT && len(d) >= rplTransitLen:
+		o.TI_External = d[0]&rplTransitExternal != 0
+		o.TI_PathControl = int(d[1])
+		o.TI_PathSeq = int(d[2])
+		o.TI_PathLifetime = int(d[3])
+		if len(d) >= rplTransitLen+16 {
+			o.TI_Parent = net.IP(d[4:20]).String()
+		}
+	case o.Type == RPL_OPT_SOLICITED_INFO && len(d) >= rplSolicitedLen:
+		o.SI_InstanceID = int(d[0])
+		o.SI_Flags = int(d[1])
+		o.SI_DODAGID = net.IP(d[2:18]).String()
+		o.SI_Version = int(d[18])
+	case o.Type == RPL_OPT_PREFIX_INFO && len(d) >= rplPrefixInfoLen:
+		o.PrefixLen = int(d[0])
+		o.PI_Flags = d[1]
+		o.PI_Valid_Time = binary.BigEndian.Uint32(d[2:6])
+		o.PI_Pref_Time = binary.BigEndian.Uint32(d[6:10])
+		o.Prefix = net.IP(d[14:30]).String()
+	default:
+		o.Raw = append([]byte(nil), b...)
+	}
+	return o
+}
@@ -0,0 +1,83 @@
+package hi6
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseRPLDIO(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1a", SrcIP: "fe80::1", Type: ICMPTypeRPLControl, Code: RPL_DIO,
+		RPL_InstanceID: 1, RPL_Version: 2, RPL_Rank: 256, RPL_Grounded: true, RPL_MOP: RPL_MOP_STORING,
+		RPL_Prf: 3, RPL_DTSN: 4, RPL_DODAGID: "2001:db8::1"}
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_DODAG_CONFIG, DC_PCS: 1, DC_DIOIntDoubl: 20, DC_DIOIntMin: 3,
+		DC_DIORedun: 10, DC_MaxRankIncrease: 2048, DC_MinHopRankIncrease: 256, DC_OCP: 1,
+		DC_DefLifetime: 30, DC_LifetimeUnit: 60})
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_PREFIX_INFO, Prefix: "2001:db8::", PrefixLen: 64,
+		PI_Flags: OPT_FLAG_AUTO, PI_Valid_Time: 100, PI_Pref_Time: 50})
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_PAD1})
+	p.AddRPLOption(RPLOption{Raw: []byte{RPL_OPT_PADN, 1, 0}})
+	q := roundTrip(t, &p)
+
+	if q.Code != RPL_DIO || q.RPL_InstanceID != 1 || q.RPL_Version != 2 || q.RPL_Rank != 256 ||
+		!q.RPL_Grounded || q.RPL_MOP != RPL_MOP_STORING || q.RPL_Prf != 3 || q.RPL_DTSN != 4 || q.RPL_DODAGID != "2001:db8::1" {
+		t.Fatalf("%+v", q)
+	}
+	if len(q.RPL_Options) != 4 {
+		t.Fatalf("got %d options", len(q.RPL_Options))
+	}
+	if !reflect.DeepEqual(q.RPL_Options[0], p.RPL_Options[0]) {
+		t.Fatalf("DODAG Configuration %+v", q.RPL_Options[0])
+	}
+	pi := q.RPL_Options[1]
+	if pi.Prefix != "2001:db8::" || pi.PrefixLen != 64 || pi.PI_Flags != OPT_FLAG_AUTO || pi.PI_Valid_Time != 100 {
+		t.Fatalf("Prefix Information %+v", pi)
+	}
+	if q.RPL_Options[2].Type != RPL_OPT_PAD1 || !bytes.Equal(q.RPL_Options[3].Raw, []byte{RPL_OPT_PADN, 1, 0}) {
+		t.Fatalf("padding %+v", q.RPL_Options[2:])
+	}
+}
+
+func TestParseRPLDAO(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55", Type: ICMPTypeRPLControl,
+		Code: RPL_DAO, RPL_InstanceID: 1, RPL_AckReq: true, RPL_DAOSeq: 9, RPL_DODAGID: "2001:db8::1"}
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_TARGET, Prefix: "2001:db8:1::", PrefixLen: 48})
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_TRANSIT, TI_External: true, TI_PathSeq: 5, TI_PathLifetime: 30, TI_Parent: "2001:db8::1"})
+	q := roundTrip(t, &p)
+
+	if q.Code != RPL_DAO || !q.RPL_AckReq || q.RPL_DAOSeq != 9 || q.RPL_DODAGID != "2001:db8::1" || len(q.RPL_Options) != 2 {
+		t.Fatalf("%+v", q)
+	}
+	if q.RPL_Options[0].Prefix != "2001:db8:1::" || q.RPL_Options[0].PrefixLen != 48 || !reflect.DeepEqual(q.RPL_Options[1], p.RPL_Options[1]) {
+		t.Fatalf("options %+v", q.RPL_Options)
+	}
+
+	p.Code, p.RPL_Status, p.RPL_Options = RPL_DAO_ACK, 128, nil
+	q = roundTrip(t, &p)
+	if q.Code != RPL_DAO_ACK || q.RPL_DAOSeq != 9 || q.RPL_Status != 128 || q.RPL_DODAGID != "2001:db8::1" {
+		t.Fatalf("DAO-ACK %+v", q)
+	}
+}
+
+func TestParseRPLDIS(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1a", SrcIP: "fe80::1", Type: ICMPTypeRPLControl, Code: RPL_DIS}
+	p.AddRPLOption(RPLOption{Type: RPL_OPT_SOLICITED_INFO, SI_InstanceID: 1,
+		SI_Flags: RPL_SI_FLAG_VERSION | RPL_SI_FLAG_DODAGID, SI_DODAGID: "2001:db8::1", SI_Version: 3})
+	q := roundTrip(t, &p)
+	if q.Code != RPL_DIS || len(q.RPL_Options) != 1 || !reflect.DeepEqual(q.RPL_Options[0], p.RPL_Options[0]) {
+		t.Fatalf("%+v", q.RPL_Options)
+	}
+}
+
+func TestParseRPLDISShort(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1a", SrcIP: "fe80::1", Type: ICMPTypeRPLControl, Code: RPL_DIS}
+	q := roundTrip(t, &p)
+
+	// type, code, checksum and the 2 byte DIS base without padding
+	if n := len(q.frame) - IPHeaderLen; n != 6 {
+		t.Fatalf("DIS is %d bytes", n)
+	}
+	if q.Code != RPL_DIS || len(q.RPL_Options) != 0 || len(q.Data) != 0 {
+		t.Fatalf("options %+v Data % x", q.RPL_Options, q.Data)
+	}
+}
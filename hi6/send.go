@@ -0,0 +1,386 @@
+package hi6
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"syscall"
+	"time"
+)
+
+// SEND Trust Anchor Name Types
+const (
+	TA_NAME_DER  = 1
+	TA_NAME_FQDN = 2
+)
+
+// SEND Certificate Types
+const (
+	CERT_X509V3 = 1
+)
+
+// SEND lengths
+const (
+	cgaModifierLen   = 16
+	cgaPrefixLen     = 8
+	cgaMaxSec        = 7
+	sigKeyHashLen    = 16
+	sigHeaderLen     = 4 + sigKeyHashLen
+	tsLen            = 16
+	nonceDefaultLen  = 6
+	sendOptHeaderLen = 4
+)
+
+// CGA Message Type tag for SEND signatures
+var cgaMessageTag = []byte{
+	0x08, 0x6f, 0xca, 0x5e, 0x10, 0xb2, 0x00, 0xc9,
+	0x9c, 0x8c, 0xe0, 0x01, 0x64, 0x27, 0x7c, 0x08,
+}
+
+// CGA holds the parameters of a Cryptographically Generated Address
+type CGA struct {
+	// Modifier found by GenerateCGA
+	Modifier [cgaModifierLen]byte
+
+	// Subnet Prefix
+	Prefix [cgaPrefixLen]byte
+
+	// Collision Count. Increment and call Address again
+	// if the address is already in use
+	CollisionCount int
+
+	// DER encoded SubjectPublicKeyInfo of Key
+	PublicKey []byte
+
+	// Extension Fields
+	Ext []byte
+
+	// Security parameter encoded in the address
+	Sec int
+
+	// RSA key the address is generated from. Used by RSASignatureOption
+	Key *rsa.PrivateKey
+}
+
+// GenerateCGA searches a Modifier for key and sec and returns the
+// CGA parameters for the 64 bit prefix. Every Sec step costs 2^16
+// times more hashes, anything over 1 takes very long
+func GenerateCGA(prefix string, key *rsa.PrivateKey, sec int) (*CGA, error) {
+	cErr := errors.New("Error generating CGA")
+
+	ip := net.ParseIP(prefix)
+	if ip == nil || key == nil || sec < 0 || sec > cgaMaxSec {
+		fmt.Println("CGA: need a prefix, a key and Sec 0 to 7")
+		return nil, cErr
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		fmt.Println(err)
+		return nil, cErr
+	}
+	c := &CGA{PublicKey: pub, Sec: sec, Key: key}
+	copy(c.Prefix[:], ip.To16())
+	if _, err := rand.Read(c.Modifier[:]); err != nil {
+		fmt.Println("CGA: could not generate modifier")
+		return nil, cErr
+	}
+
+	// Hash2 uses a zero prefix and collision count
+	in := make([]byte, cgaModifierLen+cgaPrefixLen+1, cgaModifierLen+cgaPrefixLen+1+len(pub))
+	in = append(in, pub...)
+	m := new(big.Int)
+	one := big.NewInt(1)
+	for {
+		copy(in, c.Modifier[:])
+		h := sha1.Sum(in)
+		if zeroBits(h[:14], 16*sec) {
+			break
+		}
+		m.SetBytes(c.Modifier[:])
+		m.Add(m, one)
+		b := m.Bytes()
+		if len(b) > cgaModifierLen {
+			b = b[1:]
+		}
+		c.Modifier = [cgaModifierLen]byte{}
+		copy(c.Modifier[cgaModifierLen-len(b):], b)
+	}
+	return c, nil
+}
+
+// the first n bits of b are zero
+func zeroBits(b []byte, n int) bool {
+	for i := 0; i < n/8; i++ {
+		if b[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Params returns the CGA Parameters data structure
+func (c *CGA) Params() []byte {
+	b := append([]byte(nil), c.Modifier[:]...)
+	b = append(b, c.Prefix[:]...)
+	b = append(b, byte(c.CollisionCount))
+	b = append(b, c.PublicKey...)
+	return append(b, c.Ext...)
+}
+
+// Address returns the CGA for the current parameters
+func (c *CGA) Address() string {
+	h := sha1.Sum(c.Params())
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, c.Prefix[:])
+	copy(ip[cgaPrefixLen:], h[:8])
+	// Sec in the top 3 bits, clear u and g
+	ip[cgaPrefixLen] = byte(c.Sec)<<5 | ip[cgaPrefixLen]&0x1c
+	return ip.String()
+}
+
+// CGAOption returns a CGA option carrying the parameters of c
+func CGAOption(c *CGA) Option {
+	return Option{Type: OPT_CGA, CGA_Params: c.Params()}
+}
+
+// RSASignatureOption returns an RSA Signature option signed with key.
+// It must be the last option, the signature covers the ones before it
+func RSASignatureOption(key *rsa.PrivateKey) Option {
+	return Option{Type: OPT_RSA_SIGNATURE, SIG_Key: key}
+}
+
+// LoadRSAKey reads a PKCS#1 or PKCS#8 RSA private key from a PEM file
+func LoadRSAKey(file string) (*rsa.PrivateKey, error) {
+	kErr := errors.New("Error loading RSA key")
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println(err)
+		return nil, kErr
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		fmt.Println("No PEM data in", file)
+		return nil, kErr
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		fmt.Println(err)
+		return nil, kErr
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		fmt.Println("Not an RSA key in", file)
+		return nil, kErr
+	}
+	return key, nil
+}
+
+// LoadCertificates reads every X.509 certificate from a PEM file
+func LoadCertificates(file string) ([]*x509.Certificate, error) {
+	cErr := errors.New("Error loading certificates")
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println(err)
+		return nil, cErr
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			fmt.Println(err)
+			return nil, cErr
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		fmt.Println("No certificates in", file)
+		return nil, cErr
+	}
+	return certs, nil
+}
+
+// CertificateOption returns a Certificate option carrying cert
+func CertificateOption(cert *x509.Certificate) Option {
+	return Option{Type: OPT_CERTIFICATE, CERT_Type: CERT_X509V3, CERT_Data: cert.Raw}
+}
+
+// TrustAnchorOption returns a Trust Anchor option naming
+// the subject of cert
+func TrustAnchorOption(cert *x509.Certificate) Option {
+	return Option{Type: OPT_TRUST_ANCHOR, TA_NameType: TA_NAME_DER, TA_Name: cert.RawSubject}
+}
+
+// CGA option
+func (o *Option) cgaOption() []byte {
+	b := []byte{OPT_CGA, 0, 0, 0}
+	b = append(b, o.CGA_Params...)
+	n := len(b)
+	b = padOption(b)
+	b[2] = byte(len(b) - n)
+	return b
+}
+
+// RSA Signature option with the Key Hash. The signature is left
+// zero for signSEND unless SIG_Data is set
+func (o *Option) rsaSignature() ([]byte, error) {
+	sErr := errors.New("Error building RSA Signature option")
+
+	if o.SIG_Key == nil && o.SIG_Data == nil {
+		fmt.Println("RSA Signature needs a key or signature data")
+		return nil, sErr
+	}
+	hash := o.SIG_KeyHash
+	if hash == nil && o.SIG_Key == nil {
+		fmt.Println("RSA Signature needs a key or a Key Hash")
+		return nil, sErr
+	}
+	if hash == nil {
+		pub, err := x509.MarshalPKIXPublicKey(&o.SIG_Key.PublicKey)
+		if err != nil {
+			fmt.Println(err)
+			return nil, sErr
+		}
+		h := sha1.Sum(pub)
+		hash = h[:sigKeyHashLen]
+	}
+	b := make([]byte, sigHeaderLen)
+	b[0] = OPT_RSA_SIGNATURE
+	copy(b[4:sigHeaderLen], hash)
+	if o.SIG_Data != nil {
+		b = append(b, o.SIG_Data...)
+	} else {
+		b = append(b, make([]byte, o.SIG_Key.Size())...)
+	}
+	return padOption(b), nil
+}
+
+// Timestamp option. 48 bit seconds and 16 bit 1/64K fractions
+func (o *Option) timestamp() []byte {
+	b := make([]byte, tsLen)
+	b[0] = OPT_TIMESTAMP
+	b[1] = tsLen / 8
+	ts := uint64(o.TS_Time.Unix())<<16 | uint64(o.TS_Time.Nanosecond())*0x10000/uint64(time.Second)
+	binary.BigEndian.PutUint64(b[8:16], ts)
+	return b
+}
+
+// decode a Timestamp option. b holds the whole option
+func (o *Option) parseTimestamp(b []byte) {
+	ts := binary.BigEndian.Uint64(b[8:16])
+	ns := (ts & 0xffff) * uint64(time.Second) / 0x10000
+	o.TS_Time = time.Unix(int64(ts>>16), int64(ns))
+}
+
+// Nonce option
+func (o *Option) nonce() []byte {
+	return padOption(append([]byte{OPT_NONCE, 0}, o.Nonce...))
+}
+
+// fill in the RSA Signature option at pos of the built ICMP6 message
+// and recompute the checksum. The signature covers the IPv6 addresses
+// and the message up to the option with a zero checksum
+func (t *ICMP6) signSEND(h *ip6Header, p *icmp6Header, msg []byte, pos int) error {
+	sErr := errors.New("Error signing SEND message")
+
+	o := t.sigOpt
+	msg[2] = 0
+	msg[3] = 0
+	if o.SIG_Data == nil {
+		in := append([]byte(nil), cgaMessageTag...)
+		in = append(in, h.Src.To16()...)
+		in = append(in, h.Dst.To16()...)
+		in = append(in, msg[:pos]...)
+		d := sha1.Sum(in)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, o.SIG_Key, crypto.SHA1, d[:])
+		if err != nil {
+			fmt.Println(err)
+			return sErr
+		}
+		copy(msg[pos+sigHeaderLen:], sig)
+	}
+	cs := pseudoCsum(p.Src, p.Dst, syscall.IPPROTO_ICMPV6, msg)
+	msg[2] = byte(cs)
+	msg[3] = byte(cs >> 8)
+	return nil
+}
+
+// zero pad an option to 8 bytes and set its Length
+func padOption(b []byte) []byte {
+	b = append(b, make([]byte, (8-len(b)%8)%8)...)
+	b[1] = byte(len(b) / 8)
+	return b
+}
+
+// Trust Anchor option
+func (o *Option) trustAnchor() ([]byte, error) {
+	name := o.TA_Name
+	if o.TA_NameType == TA_NAME_FQDN && o.TA_FQDN != "" {
+		var err error
+		name, err = dnsEncodeName(o.TA_FQDN)
+		if err != nil {
+			return nil, errors.New("Error building Trust Anchor option")
+		}
+	}
+	b := []byte{OPT_TRUST_ANCHOR, 0, byte(o.TA_NameType), 0}
+	b = append(b, name...)
+	n := len(b)
+	b = padOption(b)
+	b[3] = byte(len(b) - n)
+	return b, nil
+}
+
+// decode a Trust Anchor option. b holds the whole option
+func (o *Option) parseTrustAnchor(b []byte) {
+	o.TA_NameType = int(b[2])
+	name := b[4:]
+	if int(b[3]) <= len(name) {
+		name = name[:len(name)-int(b[3])]
+	}
+	if o.TA_NameType == TA_NAME_FQDN {
+		if names := dnsDecodeNames(name); len(names) > 0 {
+			o.TA_FQDN = names[0]
+		}
+	}
+	o.TA_Name = append([]byte(nil), name...)
+}
+
+// decode a Certificate option. b holds the whole option. The DER
+// length is used to drop the padding, if it does not parse the
+// padding is kept
+func (o *Option) parseCertificate(b []byte) {
+	o.CERT_Type = int(b[2])
+	cert := b[4:]
+	var v asn1.RawValue
+	if rest, err := asn1.Unmarshal(cert, &v); err == nil {
+		cert = cert[:len(cert)-len(rest)]
+	}
+	o.CERT_Data = append([]byte(nil), cert...)
+}
+
+// CPA Component and Reserved after the ICMP6 header
+func (t *ICMP6) cpaBody() []byte {
+	return []byte{byte(t.CP_Component >> 8), byte(t.CP_Component), 0, 0}
+}
@@ -0,0 +1,205 @@
+package hi6
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// self signed certificate for key
+func testCert(t *testing.T, key *rsa.PrivateKey) *x509.Certificate {
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "router.example"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestParseCertificationPath(t *testing.T) {
+	cert := testCert(t, testKey(t))
+
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeCertificationPathSolicitation,
+		ICMP6_id: 9, CP_Component: 0xffff}
+	p.AddOption(TrustAnchorOption(cert))
+	p.AddOption(Option{Type: OPT_TRUST_ANCHOR, TA_NameType: TA_NAME_FQDN, TA_FQDN: "ca.example"})
+	q := roundTrip(t, &p)
+
+	if q.ICMP6_id != 9 || q.CP_Component != 0xffff || len(q.Options) != 2 {
+		t.Fatalf("%+v", q)
+	}
+	if ta := q.Options[0]; ta.TA_NameType != TA_NAME_DER || !bytes.Equal(ta.TA_Name, cert.RawSubject) {
+		t.Fatalf("Trust Anchor %+v", ta)
+	}
+	if ta := q.Options[1]; ta.TA_NameType != TA_NAME_FQDN || ta.TA_FQDN != "ca.example" {
+		t.Fatalf("Trust Anchor %+v", ta)
+	}
+
+	p = ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeCertificationPathAdvertisement,
+		ICMP6_id: 9, CP_AllComponents: 2, CP_Component: 1}
+	p.AddOption(CertificateOption(cert))
+	q = roundTrip(t, &p)
+
+	if q.CP_AllComponents != 2 || q.CP_Component != 1 || len(q.Options) != 1 {
+		t.Fatalf("%+v", q)
+	}
+	co := q.Options[0]
+	if co.CERT_Type != CERT_X509V3 || !bytes.Equal(co.CERT_Data, cert.Raw) {
+		t.Fatalf("Certificate %+v", co)
+	}
+}
+
+func TestLoadCertificates(t *testing.T) {
+	key := testKey(t)
+	cert := testCert(t, key)
+	dir := t.TempDir()
+
+	kf := filepath.Join(dir, "key.pem")
+	kb := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	cf := filepath.Join(dir, "cert.pem")
+	cb := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	cb = append(cb, cb...)
+	if err := os.WriteFile(kf, kb, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cf, cb, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := LoadRSAKey(kf)
+	if err != nil || !k.Equal(key) {
+		t.Fatal("key differs", err)
+	}
+	certs, err := LoadCertificates(cf)
+	if err != nil || len(certs) != 2 || !certs[1].Equal(cert) {
+		t.Fatal("certificates differ", err)
+	}
+	if _, err := LoadCertificates(kf); err == nil {
+		t.Fatal("expected error for a file without certificates")
+	}
+}
+
+func TestGenerateCGA(t *testing.T) {
+	key := testKey(t)
+	c, err := GenerateCGA("2001:db8::", key, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hash2 starts with 16*Sec zero bits
+	in := append(append([]byte(nil), c.Modifier[:]...), make([]byte, 9)...)
+	in = append(in, c.PublicKey...)
+	h2 := sha1.Sum(in)
+	if h2[0] != 0 || h2[1] != 0 {
+		t.Fatalf("Hash2 % x", h2[:2])
+	}
+
+	// the interface identifier is Hash1 with Sec, u and g
+	ip := net.ParseIP(c.Address())
+	h1 := sha1.Sum(c.Params())
+	if !bytes.Equal(ip[:8], net.ParseIP("2001:db8::")[:8]) || ip[8]>>5 != 1 || ip[8]&0x03 != 0 ||
+		ip[8]&0x1c != h1[0]&0x1c || !bytes.Equal(ip[9:], h1[1:8]) {
+		t.Fatalf("address %s", ip)
+	}
+
+	c.CollisionCount++
+	if c.Address() == ip.String() {
+		t.Fatal("Collision Count does not change the address")
+	}
+	if _, err := GenerateCGA("2001:db8::", key, 8); err == nil {
+		t.Fatal("expected error for Sec 8")
+	}
+}
+
+func TestSENDSignature(t *testing.T) {
+	key := testKey(t)
+	c, err := GenerateCGA("fe80::", key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 500000000)
+	p := ICMP6{DstIP: "ff02::1:ff00:1", SrcIP: c.Address(), Type: ICMPTypeNeighborSolicitation, TargetAddr: "fe80::1"}
+	p.AddOption(Option{Type: OPT_SOURCE_LINKADDR, Addr: "10:0b:a9:aa:aa:aa"})
+	p.AddOption(CGAOption(c))
+	p.AddOption(Option{Type: OPT_TIMESTAMP, TS_Time: ts})
+	p.AddOption(Option{Type: OPT_NONCE, Nonce: []byte{1, 2, 3, 4, 5, 6}})
+	p.AddOption(RSASignatureOption(key))
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 5 {
+		t.Fatalf("got %d options", len(q.Options))
+	}
+	if !bytes.Equal(q.Options[1].CGA_Params, c.Params()) {
+		t.Fatal("CGA Parameters differ")
+	}
+	if !q.Options[2].TS_Time.Equal(ts) || !bytes.Equal(q.Options[3].Nonce, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("Timestamp %v Nonce % x", q.Options[2].TS_Time, q.Options[3].Nonce)
+	}
+	sig := q.Options[4]
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	kh := sha1.Sum(pub)
+	if !bytes.Equal(sig.SIG_KeyHash, kh[:16]) {
+		t.Fatal("Key Hash differs")
+	}
+
+	// the signature covers the message up to the RSA Signature option
+	// with a zero checksum
+	msg := append([]byte(nil), q.frame[IPHeaderLen:]...)
+	msg[2], msg[3] = 0, 0
+	pos := len(msg) - sigHeaderLen - len(sig.SIG_Data)
+	in := append(append([]byte(nil), cgaMessageTag...), net.ParseIP(p.SrcIP).To16()...)
+	in = append(in, net.ParseIP(p.DstIP).To16()...)
+	in = append(in, msg[:pos]...)
+	d := sha1.Sum(in)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, d[:], sig.SIG_Data[:key.Size()]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSENDSignatureData(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xaa}, 16)
+	sig := bytes.Repeat([]byte{0x55}, 124)
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterSolicitation}
+	p.AddOption(Option{Type: OPT_RSA_SIGNATURE, SIG_KeyHash: hash, SIG_Data: sig})
+	q := roundTrip(t, &p)
+
+	o := q.Options[0]
+	if !bytes.Equal(o.SIG_KeyHash, hash) || !bytes.Equal(o.SIG_Data, sig) {
+		t.Fatalf("%+v", o)
+	}
+
+	// a chosen signature without a key needs the Key Hash
+	p = ICMP6{Iface: "lo", DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterSolicitation}
+	p.AddOption(Option{Type: OPT_RSA_SIGNATURE, SIG_Data: sig})
+	if err := p.BuildICMPPacket(); err == nil {
+		t.Fatal("expected error without SIG_Key or SIG_KeyHash")
+	}
+}
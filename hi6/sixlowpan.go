@@ -0,0 +1,87 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Address Registration Status (DAR/DAC and ARO)
+const (
+	ARO_STATUS_SUCCESS          = 0
+	ARO_STATUS_DUPLICATE        = 1
+	ARO_STATUS_CACHE_FULL       = 2
+	ARO_STATUS_MOVED            = 3
+	ARO_STATUS_REMOVED          = 4
+	ARO_STATUS_VALIDATION_REQ   = 5
+	ARO_STATUS_DUPLICATE_SOURCE = 6
+	ARO_STATUS_INVALID_SOURCE   = 7
+	ARO_STATUS_TOPO_INCORRECT   = 8
+	ARO_STATUS_REGISTRY_FULL    = 9
+	ARO_STATUS_VALIDATION_FAIL  = 10
+)
+
+// Extended Address Registration Option Flags. The I field
+// is ARO_FLAG_I_SHIFT bits up
+const (
+	ARO_FLAG_REGISTRATION = 0x02
+	ARO_FLAG_TID          = 0x01
+	ARO_FLAG_I_SHIFT      = 2
+)
+
+// 6LoWPAN Context Option Flags
+const (
+	CO_FLAG_COMPRESS = 0x10
+	CO_CID_MASK      = 0x0f
+)
+
+// 6LoWPAN-ND lengths
+const (
+	eui64Len      = 8
+	aroLen        = 16
+	coShortLen    = 16
+	coLongLen     = 24
+	coShortPrefix = 64
+	darRegAddrLen = 16
+)
+
+// pad a ROVR to 8 bytes. An empty ROVR is a zero EUI-64
+func padROVR(rovr []byte) []byte {
+	b := make([]byte, (len(rovr)+7)&^7)
+	if len(b) == 0 {
+		b = make([]byte, eui64Len)
+	}
+	copy(b, rovr)
+	return b
+}
+
+// Duplicate Address Request/Confirmation body after the ICMP6
+// header. The EUI-64 or ROVR is followed by the Registered Address
+func (t *ICMP6) darBody() ([]byte, error) {
+	dErr := errors.New("Error building Duplicate Address message")
+
+	ip := net.ParseIP(t.DA_Addr)
+	if ip == nil {
+		fmt.Println("DAR: could not parse registered address")
+		return nil, dErr
+	}
+	b := padROVR(t.DA_ROVR)
+	return append(b, ip.To16()...), nil
+}
+
+// decode the Duplicate Address Request/Confirmation fields. data
+// is the ICMP6 header data and body everything after it
+func (t *ICMP6) parseDAR(data []byte, body []byte) []byte {
+	t.DA_Status = int(data[0])
+	t.DA_TID = int(data[1])
+	t.DA_Lifetime = binary.BigEndian.Uint16(data[2:4])
+	if len(body) < eui64Len+darRegAddrLen {
+		return body
+	}
+	// the ROVR takes everything before the Registered Address
+	n := len(body) - darRegAddrLen
+	t.DA_ROVR = append([]byte(nil), body[:n]...)
+	t.DA_Addr = net.IP(body[n:]).String()
+	return nil
+}
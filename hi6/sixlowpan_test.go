@@ -0,0 +1,47 @@
+package hi6
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseDuplicateAddressRequest(t *testing.T) {
+	rovr := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeDuplicateAddressRequest, DA_TID: 7, DA_Lifetime: 60, DA_ROVR: rovr, DA_Addr: "2001:db8::5"}
+	q := roundTrip(t, &p)
+	if q.DA_TID != 7 || q.DA_Lifetime != 60 || !bytes.Equal(q.DA_ROVR, rovr) || q.DA_Addr != "2001:db8::5" {
+		t.Fatalf("%+v", q)
+	}
+
+	// an empty ROVR is sent as a zero EUI-64
+	p.Type, p.DA_Status, p.DA_ROVR = ICMPTypeDuplicateAddressConfirmation, ARO_STATUS_DUPLICATE, nil
+	q = roundTrip(t, &p)
+	if q.DA_Status != ARO_STATUS_DUPLICATE || !bytes.Equal(q.DA_ROVR, make([]byte, 8)) {
+		t.Fatalf("%+v", q)
+	}
+}
+
+func TestParse6LoWPANOptions(t *testing.T) {
+	p := ICMP6{DstIP: "ff02::1", SrcIP: "fe80::1", Type: ICMPTypeRouterAdvertisement}
+	p.AddOption(Option{Type: OPT_ADDR_REGISTRATION, ARO_Status: ARO_STATUS_SUCCESS, ARO_Flags: ARO_FLAG_REGISTRATION,
+		ARO_TID: 3, ARO_Lifetime: 120, ARO_ROVR: []byte{1, 2, 3, 4, 5, 6, 7, 8}})
+	p.AddOption(Option{Type: OPT_6LOWPAN_CONTEXT, CO_Len: 64, CO_Compress: true, CO_CID: 2, CO_Lifetime: 30, Addr: "2001:db8::"})
+	p.AddOption(Option{Type: OPT_6LOWPAN_CONTEXT, CO_Len: 96, CO_CID: 1, Addr: "2001:db8:1:2:3:4::"})
+	q := roundTrip(t, &p)
+
+	if len(q.Options) != 3 {
+		t.Fatalf("got %d options", len(q.Options))
+	}
+	aro := q.Options[0]
+	if aro.ARO_Flags != ARO_FLAG_REGISTRATION || aro.ARO_TID != 3 || aro.ARO_Lifetime != 120 || !bytes.Equal(aro.ARO_ROVR, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("ARO %+v", aro)
+	}
+	co := q.Options[1]
+	if co.CO_Len != 64 || !co.CO_Compress || co.CO_CID != 2 || co.CO_Lifetime != 30 || co.Addr != "2001:db8::" {
+		t.Fatalf("6CO %+v", co)
+	}
+	if q.Options[2].CO_Len != 96 || q.Options[2].Addr != "2001:db8:1:2:3:4::" {
+		t.Fatalf("long 6CO %+v", q.Options[2])
+	}
+}
@@ -0,0 +1,144 @@
+package hi6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Extended Echo Reply Codes
+const (
+	XE_NO_ERROR            = 0
+	XE_MALFORMED_QUERY     = 1
+	XE_NO_SUCH_INTERFACE   = 2
+	XE_NO_SUCH_TABLE_ENTRY = 3
+	XE_MULTIPLE_INTERFACES = 4
+)
+
+// Extended Echo Reply States
+const (
+	XE_STATE_RESERVED   = 0
+	XE_STATE_INCOMPLETE = 1
+	XE_STATE_REACHABLE  = 2
+	XE_STATE_STALE      = 3
+	XE_STATE_DELAY      = 4
+	XE_STATE_PROBE      = 5
+	XE_STATE_FAILED     = 6
+)
+
+// Extended Echo Flags
+const (
+	XE_FLAG_LOCAL  = 0x01 /* request */
+	XE_FLAG_ACTIVE = 0x04 /* reply */
+	XE_FLAG_IPV4   = 0x02
+	XE_FLAG_IPV6   = 0x01
+)
+
+// ICMP Extension Structure and Interface Identification Object
+const (
+	icmpExtVersion        = 2
+	icmpExtHeaderLen      = 4
+	icmpExtObjHeaderLen   = 4
+	icmpExtClassInterface = 3
+	icmpExtCTypeName      = 1
+	icmpExtCTypeIndex     = 2
+	icmpExtCTypeAddr      = 3
+	afiIPv4               = 1
+	afiIPv6               = 2
+)
+
+// ICMP Extension Structure with the Interface Identification Object
+// of an Extended Echo Request. Identifies the interface by name,
+// address or index, in that order of preference
+func (t *ICMP6) xechoBody() ([]byte, error) {
+	xErr := errors.New("Error building Extended Echo Request")
+
+	var ctype int
+	var obj []byte
+	switch {
+	case t.XE_IfName != "":
+		ctype = icmpExtCTypeName
+		obj = make([]byte, (len(t.XE_IfName)+3)&^3)
+		copy(obj, t.XE_IfName)
+	case t.XE_IfAddr != "":
+		ctype = icmpExtCTypeAddr
+		ip := net.ParseIP(t.XE_IfAddr)
+		if ip == nil {
+			fmt.Println("Extended Echo: could not parse interface address")
+			return nil, xErr
+		}
+		afi, addr := afiIPv6, ip.To16()
+		if ip4 := ip.To4(); ip4 != nil {
+			afi, addr = afiIPv4, ip4
+		}
+		obj = make([]byte, 4, 4+len(addr))
+		binary.BigEndian.PutUint16(obj[0:2], uint16(afi))
+		obj[2] = byte(len(addr))
+		obj = append(obj, addr...)
+	case t.XE_IfIndex != 0:
+		ctype = icmpExtCTypeIndex
+		obj = make([]byte, 4)
+		binary.BigEndian.PutUint32(obj, t.XE_IfIndex)
+	default:
+		// no object, a malformed query
+		return nil, nil
+	}
+
+	b := make([]byte, icmpExtHeaderLen+icmpExtObjHeaderLen, icmpExtHeaderLen+icmpExtObjHeaderLen+len(obj))
+	b[0] = icmpExtVersion << 4
+	binary.BigEndian.PutUint16(b[4:6], uint16(icmpExtObjHeaderLen+len(obj)))
+	b[6] = icmpExtClassInterface
+	b[7] = byte(ctype)
+	b = append(b, obj...)
+
+	cs := csum(b)
+	b[2] = byte(cs)
+	b[3] = byte(cs >> 8)
+	return b, nil
+}
+
+// decode the Extended Echo fields. data is the ICMP6 header
+// data and body everything after it
+func (t *ICMP6) parseXEcho(data []byte, body []byte) []byte {
+	t.ICMP6_id = binary.BigEndian.Uint16(data[0:2])
+	t.ICMP6_seq = uint16(data[2])
+
+	if t.Type == ICMPTypeExtendedEchoReply {
+		t.XE_State = int(data[3] >> 5)
+		t.XE_Active = data[3]&XE_FLAG_ACTIVE != 0
+		t.XE_IPv4 = data[3]&XE_FLAG_IPV4 != 0
+		t.XE_IPv6 = data[3]&XE_FLAG_IPV6 != 0
+		return body
+	}
+
+	t.XE_Local = data[3]&XE_FLAG_LOCAL != 0
+	if len(body) < icmpExtHeaderLen+icmpExtObjHeaderLen || body[0]>>4 != icmpExtVersion {
+		return body
+	}
+	ob := body[icmpExtHeaderLen:]
+	l := int(binary.BigEndian.Uint16(ob[0:2]))
+	if l < icmpExtObjHeaderLen || l > len(ob) || ob[2] != icmpExtClassInterface {
+		return body
+	}
+	obj := ob[icmpExtObjHeaderLen:l]
+	switch ob[3] {
+	case icmpExtCTypeName:
+		n := 0
+		for n < len(obj) && obj[n] != 0 {
+			n++
+		}
+		t.XE_IfName = string(obj[:n])
+	case icmpExtCTypeIndex:
+		if len(obj) >= 4 {
+			t.XE_IfIndex = binary.BigEndian.Uint32(obj)
+		}
+	case icmpExtCTypeAddr:
+		if len(obj) >= 4 && 4+int(obj[2]) <= len(obj) {
+			t.XE_IfAddr = net.IP(obj[4 : 4+int(obj[2])]).String()
+		}
+	default:
+		return body
+	}
+	return ob[l:]
+}
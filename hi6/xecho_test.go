@@ -0,0 +1,36 @@
+package hi6
+
+import "testing"
+
+func TestParseExtendedEchoRequest(t *testing.T) {
+	tests := []ICMP6{
+		{XE_IfName: "eth0"},
+		{XE_IfIndex: 3},
+		{XE_IfAddr: "2001:db8::5"},
+		{XE_IfAddr: "192.0.2.1"},
+	}
+	for _, p := range tests {
+		p.DstIP, p.SrcIP, p.DstMAC = "2001:db8::1", "2001:db8::2", "00:11:22:33:44:55"
+		p.Type = ICMPTypeExtendedEchoRequest
+		p.ICMP6_id, p.ICMP6_seq, p.XE_Local = 0x1234, 7, true
+		q := roundTrip(t, &p)
+
+		if q.ICMP6_id != 0x1234 || q.ICMP6_seq != 7 || !q.XE_Local ||
+			q.XE_IfName != p.XE_IfName || q.XE_IfIndex != p.XE_IfIndex || q.XE_IfAddr != p.XE_IfAddr {
+			t.Errorf("sent %+v got name %q index %d addr %q", p, q.XE_IfName, q.XE_IfIndex, q.XE_IfAddr)
+		}
+		if len(q.Data) != 0 {
+			t.Errorf("extension object left in Data % x", q.Data)
+		}
+	}
+}
+
+func TestParseExtendedEchoReply(t *testing.T) {
+	p := ICMP6{DstIP: "2001:db8::1", SrcIP: "2001:db8::2", DstMAC: "00:11:22:33:44:55",
+		Type: ICMPTypeExtendedEchoReply, ICMP6_id: 1, ICMP6_seq: 2, Code: XE_NO_ERROR,
+		XE_State: XE_STATE_REACHABLE, XE_Active: true, XE_IPv6: true}
+	q := roundTrip(t, &p)
+	if q.XE_State != XE_STATE_REACHABLE || !q.XE_Active || !q.XE_IPv6 || q.XE_IPv4 || q.ICMP6_seq != 2 {
+		t.Fatalf("%+v", q)
+	}
+}